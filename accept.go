@@ -0,0 +1,110 @@
+package httpsign
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+const acceptSignatureHeader = "Accept-Signature"
+
+// AcceptSignature describes a signature requested by a [Middleware] in the Accept-Signature field
+// (RFC 9421 Section 5.1) of responses to requests that fail verification.
+type AcceptSignature struct {
+	// Label is the label of the requested signature.
+	// If empty, the label is "sigN", where N is the position of the signature starting at 1.
+	Label string
+	// Components are the derived components and fields to be covered by the signature, in order.
+	Components []string
+	// KeyID is the requested keyid signature parameter. If empty, any key is accepted.
+	KeyID string
+	// Algorithm is the requested alg signature parameter. If empty, any algorithm is accepted.
+	Algorithm string
+}
+
+// acceptSignatureField returns the value of the Accept-Signature field requesting the signatures.
+// The created parameter is always requested, and the nonce parameter is requested if nonce is true.
+func acceptSignatureField(accepts []AcceptSignature, nonce bool) (string, error) {
+	d := make(structfield.Dictionary, 0, len(accepts))
+	for i, a := range accepts {
+		var l structfield.InnerList
+		for _, c := range componentIDs(a.Components, false) {
+			l.Items = append(l.Items, c.item())
+		}
+		l.Params = append(l.Params, structfield.Param{Key: "created", Value: true})
+		if nonce {
+			l.Params = append(l.Params, structfield.Param{Key: "nonce", Value: true})
+		}
+		if a.KeyID != "" {
+			l.Params = append(l.Params, structfield.Param{Key: "keyid", Value: a.KeyID})
+		}
+		if a.Algorithm != "" {
+			l.Params = append(l.Params, structfield.Param{Key: "alg", Value: a.Algorithm})
+		}
+		label := a.Label
+		if label == "" {
+			label = "sig" + strconv.Itoa(i+1)
+		}
+		d = append(d, structfield.DictMember{Key: label, Value: l})
+	}
+	return d.Serialize()
+}
+
+// acceptedSignature is a signature requested in the Accept-Signature field of a response.
+type acceptedSignature struct {
+	label      string
+	components []componentID
+	keyID      string
+	alg        string
+	nonce      bool
+}
+
+// parseAcceptSignature parses the requested signatures of the Accept-Signature field of h.
+// Members that cannot be satisfied by a request signature are skipped.
+func parseAcceptSignature(h http.Header) ([]acceptedSignature, error) {
+	d, err := parseDictionaryField(h, acceptSignatureHeader)
+	if err != nil {
+		return nil, err
+	}
+	var accepts []acceptedSignature
+	for _, m := range d {
+		l, ok := m.Value.(structfield.InnerList)
+		if !ok {
+			continue
+		}
+		a, ok := parseAcceptedSignature(m.Key, l)
+		if ok {
+			accepts = append(accepts, a)
+		}
+	}
+	return accepts, nil
+}
+
+func parseAcceptedSignature(label string, l structfield.InnerList) (acceptedSignature, bool) {
+	a := acceptedSignature{label: label}
+	for _, it := range l.Items {
+		c, err := parseComponentID(it)
+		if err != nil || c.req {
+			return acceptedSignature{}, false
+		}
+		a.components = append(a.components, c)
+	}
+	var ok bool
+	for _, p := range l.Params {
+		switch p.Key {
+		case "keyid":
+			a.keyID, ok = p.Value.(string)
+		case "alg":
+			a.alg, ok = p.Value.(string)
+		case "nonce":
+			a.nonce, ok = p.Value.(bool)
+		default:
+			ok = true
+		}
+		if !ok {
+			return acceptedSignature{}, false
+		}
+	}
+	return a, true
+}
@@ -0,0 +1,97 @@
+package httpsign
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAcceptSignatureField(t *testing.T) {
+	accepts := []AcceptSignature{
+		{Components: []string{"@method", "@path"}, KeyID: "a"},
+		{Label: "rsa", Components: []string{"@target-uri", "Content-Digest"}, Algorithm: AlgorithmRSAPSSSHA512},
+	}
+	got, err := acceptSignatureField(accepts, true)
+	if err != nil {
+		t.Fatalf("acceptSignatureField() error: %v", err)
+	}
+	want := `sig1=("@method" "@path");created;nonce;keyid="a", rsa=("@target-uri" "content-digest");created;nonce;alg="rsa-pss-sha512"`
+	if got != want {
+		t.Errorf("acceptSignatureField() = %q, want %q", got, want)
+	}
+
+	parsed, err := parseAcceptSignature(http.Header{acceptSignatureHeader: {got + `, req=("@method";req), item=1`}})
+	if err != nil {
+		t.Fatalf("parseAcceptSignature() error: %v", err)
+	}
+	if len(parsed) != 2 {
+		t.Fatalf("parseAcceptSignature() = %+v, want 2 signatures", parsed)
+	}
+	if a := parsed[0]; a.label != "sig1" || len(a.components) != 2 || a.keyID != "a" || !a.nonce {
+		t.Errorf("parseAcceptSignature()[0] = %+v", a)
+	}
+	if a := parsed[1]; a.label != "rsa" || a.alg != AlgorithmRSAPSSSHA512 {
+		t.Errorf("parseAcceptSignature()[1] = %+v", a)
+	}
+}
+
+func TestTransport_Negotiate(t *testing.T) {
+	tests := []struct {
+		name      string
+		negotiate bool
+		keyID     string
+		accept    []AcceptSignature
+		code      int
+		requests  int32
+	}{
+		{"disabled", false, "", []AcceptSignature{{Components: DefaultComponents}}, http.StatusUnauthorized, 1},
+		{"no accept", true, "", nil, http.StatusUnauthorized, 1},
+		{"retry", true, "", []AcceptSignature{{Components: []string{ComponentMethod}}}, http.StatusOK, 2},
+		{"key", true, "a", []AcceptSignature{{KeyID: "b"}, {KeyID: "a", Components: []string{ComponentPath}}}, http.StatusOK, 2},
+		{"unsatisfiable", true, "a", []AcceptSignature{{KeyID: "b"}}, http.StatusUnauthorized, 1},
+		{"field", true, "", []AcceptSignature{{Components: []string{ComponentMethod, "Content-Type"}}}, http.StatusOK, 2},
+		{"missing field", true, "", []AcceptSignature{{Components: []string{ComponentMethod, "x-tenant"}}}, http.StatusUnauthorized, 1},
+		{"invalid accept", true, "", []AcceptSignature{{Label: "Invalid", Components: []string{ComponentMethod}}}, http.StatusUnauthorized, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMiddleware(stubVerifier{})
+			m.NonceStore = NewMemoryNonceStore()
+			m.AcceptSignatures = tt.accept
+			var requests atomic.Int32
+			h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				h.ServeHTTP(w, r)
+			}))
+			defer s.Close()
+
+			tr := NewTransport(stubSigner{})
+			tr.Nonce = nil // signatures without a nonce are rejected by the Middleware.
+			tr.KeyID = tt.keyID
+			tr.Negotiate = tt.negotiate
+			c := http.Client{Transport: tr}
+			resp, err := c.Post(s.URL, "text/plain", strings.NewReader("hello"))
+			if err != nil {
+				t.Fatalf("Post() error: %v", err)
+			}
+			if _, err := io.ReadAll(resp.Body); err != nil {
+				t.Errorf("ReadAll() error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Errorf("code: %d, want %d", resp.StatusCode, tt.code)
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("requests = %d, want %d", got, tt.requests)
+			}
+			_, aerr := acceptSignatureField(tt.accept, true)
+			if got := resp.Header.Get(acceptSignatureHeader); (got != "") != (len(tt.accept) > 0 && aerr == nil && tt.code == http.StatusUnauthorized) {
+				t.Errorf("%s = %q", acceptSignatureHeader, got)
+			}
+		})
+	}
+}
@@ -1,13 +1,84 @@
 package httpsign
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrMalformedSignature is returned by a [Verifier] when a signature cannot be a valid signature for its key,
+// for example because it has the wrong length.
+var ErrMalformedSignature = errors.New("malformed signature")
+
+// Algorithm names from the HTTP Signature Algorithms registry (RFC 9421 Section 6.2).
+const (
+	AlgorithmRSAPSSSHA512    = "rsa-pss-sha512"
+	AlgorithmRSAPKCSSHA256   = "rsa-v1_5-sha256"
+	AlgorithmHMACSHA256      = "hmac-sha256"
+	AlgorithmECDSAP256SHA256 = "ecdsa-p256-sha256"
+	AlgorithmECDSAP384SHA384 = "ecdsa-p384-sha384"
+	AlgorithmEd25519         = "ed25519"
+)
+
 // Signer signs messages.
 // It must be safe for concurrent use by multiple goroutines.
+//
+// If the Signer has an Algorithm() string method returning a non-empty name,
+// the name is sent as the alg signature parameter.
 type Signer interface {
 	Sign(message []byte) ([]byte, error)
 }
 
 // Verifier verifies message signatures.
 // It must be safe for concurrent use by multiple goroutines.
+//
+// Verify reports the result as follows:
+//   - If the signature is valid, it returns true and a nil error.
+//   - If the signature is well-formed but does not match the message, it returns false and a nil error.
+//   - If the signature is malformed, it returns false and an error wrapping [ErrMalformedSignature].
+//   - If verification could not be performed, it returns false and any other error.
+//
+// If the Verifier has an Algorithm() string method returning a non-empty name,
+// signatures with a different alg signature parameter are rejected.
 type Verifier interface {
 	Verify(message []byte, signature []byte) (bool, error)
 }
+
+// ContextSigner is a [Signer] which signs messages with a context,
+// for example a Signer backed by a remote key service which honors request deadlines and cancellation.
+// [Transport] and [ResponseSigner] call SignContext with the request context instead of Sign.
+type ContextSigner interface {
+	Signer
+	SignContext(ctx context.Context, message []byte) ([]byte, error)
+}
+
+// ContextVerifier is a [Verifier] which verifies message signatures with a context.
+// [Middleware] and [Transport] call VerifyContext with the request context instead of Verify.
+// VerifyContext reports the result as Verify does.
+type ContextVerifier interface {
+	Verifier
+	VerifyContext(ctx context.Context, message []byte, signature []byte) (bool, error)
+}
+
+// sign signs the message with the signer, passing ctx to a ContextSigner.
+func sign(ctx context.Context, signer Signer, message []byte) ([]byte, error) {
+	if s, ok := signer.(ContextSigner); ok {
+		return s.SignContext(ctx, message)
+	}
+	return signer.Sign(message)
+}
+
+// verify verifies the signature of the message with the verifier, passing ctx to a ContextVerifier.
+func verify(ctx context.Context, verifier Verifier, message, signature []byte) (bool, error) {
+	if v, ok := verifier.(ContextVerifier); ok {
+		return v.VerifyContext(ctx, message, signature)
+	}
+	return verifier.Verify(message, signature)
+}
+
+// algorithm returns the registered algorithm name of a Signer or Verifier, if any.
+func algorithm(v any) string {
+	if a, ok := v.(interface{ Algorithm() string }); ok {
+		return a.Algorithm()
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package httpsign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+// signatureParams are the signature parameters of RFC 9421 Section 2.3.
+type signatureParams struct {
+	components []componentID
+	created    time.Time
+	expires    time.Time
+	nonce      string
+	keyID      string
+	alg        string
+}
+
+// innerList returns the signature parameters serialized as the value of the Signature-Input field member.
+func (sp signatureParams) innerList() structfield.InnerList {
+	var l structfield.InnerList
+	for _, c := range sp.components {
+		l.Items = append(l.Items, c.item())
+	}
+	if !sp.created.IsZero() {
+		l.Params = append(l.Params, structfield.Param{Key: "created", Value: sp.created.Unix()})
+	}
+	if !sp.expires.IsZero() {
+		l.Params = append(l.Params, structfield.Param{Key: "expires", Value: sp.expires.Unix()})
+	}
+	if sp.nonce != "" {
+		l.Params = append(l.Params, structfield.Param{Key: "nonce", Value: sp.nonce})
+	}
+	if sp.keyID != "" {
+		l.Params = append(l.Params, structfield.Param{Key: "keyid", Value: sp.keyID})
+	}
+	if sp.alg != "" {
+		l.Params = append(l.Params, structfield.Param{Key: "alg", Value: sp.alg})
+	}
+	return l
+}
+
+// parseSignatureParams parses the value of a Signature-Input field member.
+func parseSignatureParams(l structfield.InnerList) (signatureParams, error) {
+	var sp signatureParams
+	for _, it := range l.Items {
+		c, err := parseComponentID(it)
+		if err != nil {
+			return signatureParams{}, err
+		}
+		sp.components = append(sp.components, c)
+	}
+	for _, p := range l.Params {
+		var ok bool
+		switch p.Key {
+		case "created":
+			var v int64
+			v, ok = p.Value.(int64)
+			sp.created = time.Unix(v, 0)
+		case "expires":
+			var v int64
+			v, ok = p.Value.(int64)
+			sp.expires = time.Unix(v, 0)
+		case "nonce":
+			sp.nonce, ok = p.Value.(string)
+		case "keyid":
+			sp.keyID, ok = p.Value.(string)
+		case "alg":
+			sp.alg, ok = p.Value.(string)
+		default:
+			ok = true // unknown parameters are covered by the signature but otherwise ignored.
+		}
+		if !ok {
+			return signatureParams{}, fmt.Errorf("invalid %q parameter", p.Key)
+		}
+	}
+	return sp, nil
+}
+
+// componentError is an error computing the value of a covered component.
+type componentError struct {
+	id  string // serialized component identifier
+	err error
+}
+
+func (e *componentError) Error() string { return fmt.Sprintf("component %s: %v", e.id, e.err) }
+
+func (e *componentError) Unwrap() error { return e.err }
+
+// signatureBase returns the signature base of the message for the signature parameters (RFC 9421 Section 2.5).
+func signatureBase(m message, params structfield.InnerList) ([]byte, error) {
+	var b strings.Builder
+	seen := make(map[string]bool, len(params.Items))
+	for _, it := range params.Items {
+		id, err := it.Serialize()
+		if err != nil {
+			return nil, &componentError{id: fmt.Sprint(it.Value), err: err}
+		}
+		if seen[id] {
+			return nil, &componentError{id: id, err: errors.New("duplicate component")}
+		}
+		seen[id] = true
+
+		c, err := parseComponentID(it)
+		if err != nil {
+			return nil, &componentError{id: id, err: fmt.Errorf("%w: %w", errUnknownComponent, err)}
+		}
+		v, err := componentValue(m, c)
+		if err != nil {
+			return nil, &componentError{id: id, err: err}
+		}
+		b.WriteString(id)
+		b.WriteString(": ")
+		b.WriteString(v)
+		b.WriteByte('\n')
+	}
+	sp, err := params.Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("signature parameters: %w", err)
+	}
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(sp)
+	return []byte(b.String()), nil
+}
+
+// signMessage signs the message, adding the signature with the label to its Signature-Input and Signature fields.
+// It fails if the message already has a signature with the label.
+func signMessage(ctx context.Context, signer Signer, m message, label string, sp signatureParams) error {
+	inputs, err := parseDictionaryField(m.header, signatureInputHeader)
+	if err != nil {
+		return err
+	}
+	if _, ok := inputs.Get(label); ok {
+		return fmt.Errorf("signature label %q is already used", label)
+	}
+	params := sp.innerList()
+	base, err := signatureBase(m, params)
+	if err != nil {
+		return err
+	}
+	sig, err := sign(ctx, signer, base)
+	if err != nil {
+		return err
+	}
+	input, err := structfield.Dictionary{{Key: label, Value: params}}.Serialize()
+	if err != nil {
+		return err
+	}
+	signature, err := structfield.Dictionary{{Key: label, Value: structfield.Item{Value: sig}}}.Serialize()
+	if err != nil {
+		return err
+	}
+	m.header.Add(signatureInputHeader, input)
+	m.header.Add(signatureHeader, signature)
+	return nil
+}
+
+// newNonce returns a nonce generated by f, or an empty string if f is nil.
+func newNonce(f func() (string, error)) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	nonce, err := f()
+	if err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	return nonce, nil
+}
@@ -0,0 +1,87 @@
+package httpsign
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+func TestSignatureBase(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://Example.com:80/foo?param=Value&Pet=dog", nil)
+	params := signatureParams{
+		components: componentIDs([]string{"@method", "@authority", "@path", "@query"}, false),
+		created:    time.Unix(1618884473, 0),
+		keyID:      "test-key",
+		alg:        "ed25519",
+	}.innerList()
+
+	base, err := signatureBase(requestMessage(r), params)
+	if err != nil {
+		t.Fatalf("signatureBase() error: %v", err)
+	}
+	want := `"@method": POST
+"@authority": example.com
+"@path": /foo
+"@query": ?param=Value&Pet=dog
+"@signature-params": ("@method" "@authority" "@path" "@query");created=1618884473;keyid="test-key";alg="ed25519"`
+	if string(base) != want {
+		t.Errorf("signatureBase() = %q, want %q", base, want)
+	}
+}
+
+func TestSignatureBase_Errors(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	tests := []signatureParams{
+		{components: componentIDs([]string{"@method", "@method"}, false)},
+		{components: componentIDs([]string{"@unknown"}, false)},
+		{components: []componentID{{name: "@method", req: true}}},
+		{components: []componentID{{name: "@method", key: "a"}}},
+	}
+	for _, sp := range tests {
+		if _, err := signatureBase(requestMessage(r), sp.innerList()); err == nil {
+			t.Errorf("signatureBase(%v) error is nil", sp.components)
+		}
+	}
+}
+
+func TestParseSignatureParams(t *testing.T) {
+	d, err := structfield.ParseDictionary(`sig1=("@method" "@path");created=1618884473;expires=1618884773;keyid="test-key";nonce="abc"`)
+	if err != nil {
+		t.Fatalf("structfield.ParseDictionary() error: %v", err)
+	}
+	sp, err := parseSignatureParams(d[0].Value.(structfield.InnerList))
+	if err != nil {
+		t.Fatalf("parseSignatureParams() error: %v", err)
+	}
+	if len(sp.components) != 2 || sp.components[0].name != "@method" || sp.components[1].name != "@path" {
+		t.Errorf("components = %q, want [@method @path]", sp.components)
+	}
+	if sp.created.Unix() != 1618884473 {
+		t.Errorf("created = %d, want %d", sp.created.Unix(), 1618884473)
+	}
+	if sp.expires.Unix() != 1618884773 {
+		t.Errorf("expires = %d, want %d", sp.expires.Unix(), 1618884773)
+	}
+	if sp.keyID != "test-key" {
+		t.Errorf("keyid = %q, want %q", sp.keyID, "test-key")
+	}
+
+	for _, in := range []string{
+		`sig1=(method)`,
+		`sig1=("@method");created="now"`,
+		`sig1=("@method");keyid=1`,
+		`sig1=("@method");expires=1.5`,
+		`sig1=("@method";req=1)`,
+		`sig1=("@method";bs)`,
+	} {
+		d, err := structfield.ParseDictionary(in)
+		if err != nil {
+			t.Fatalf("structfield.ParseDictionary(%q) error: %v", in, err)
+		}
+		if _, err := parseSignatureParams(d[0].Value.(structfield.InnerList)); err == nil {
+			t.Errorf("parseSignatureParams(%q) error is nil", in)
+		}
+	}
+}
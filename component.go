@@ -0,0 +1,298 @@
+package httpsign
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+// Derived component names (RFC 9421 Section 2.2).
+const (
+	ComponentMethod        = "@method"
+	ComponentTargetURI     = "@target-uri"
+	ComponentAuthority     = "@authority"
+	ComponentScheme        = "@scheme"
+	ComponentRequestTarget = "@request-target"
+	ComponentPath          = "@path"
+	ComponentQuery         = "@query"
+	ComponentStatus        = "@status"
+)
+
+// DefaultComponents are the components covered by [Transport] signatures by default.
+var DefaultComponents = []string{ComponentMethod, ComponentAuthority, ComponentPath, ComponentQuery}
+
+// MissingHeaderPolicy defines how header fields to be covered by a signature but missing from a message are handled.
+type MissingHeaderPolicy int
+
+const (
+	// MissingHeaderFail fails signing the message.
+	MissingHeaderFail MissingHeaderPolicy = iota
+	// MissingHeaderSkip leaves the missing header field uncovered.
+	MissingHeaderSkip
+)
+
+// headerComponents returns the identifiers of the header fields with the names covered by a signature of a message
+// with the header h, following the policy for missing fields.
+func headerComponents(h http.Header, names []string, policy MissingHeaderPolicy) ([]componentID, error) {
+	ids := make([]componentID, 0, len(names))
+	for _, name := range names {
+		if len(h.Values(name)) == 0 {
+			if policy == MissingHeaderSkip {
+				continue
+			}
+			return nil, &componentError{id: fmt.Sprintf("%q", strings.ToLower(name)), err: errMissingField}
+		}
+		ids = append(ids, componentID{name: strings.ToLower(name)})
+	}
+	return ids, nil
+}
+
+var (
+	errUnknownComponent = errors.New("unknown component")
+	errMissingField     = errors.New("missing field")
+)
+
+// componentID is a component identifier (RFC 9421 Section 2): a component name with its parameters.
+type componentID struct {
+	name string
+	// key is the key of the covered member of a dictionary field (RFC 9421 Section 2.1.2).
+	key string
+	// req reports whether the component is of the request a response answers (RFC 9421 Section 2.4).
+	req bool
+}
+
+// componentIDs returns the identifiers of the components with the names.
+// Field names are lowercased, as required by RFC 9421 Section 2.1.
+func componentIDs(names []string, req bool) []componentID {
+	ids := make([]componentID, len(names))
+	for i, name := range names {
+		if !strings.HasPrefix(name, "@") {
+			name = strings.ToLower(name)
+		}
+		ids[i] = componentID{name: name, req: req}
+	}
+	return ids
+}
+
+// item returns the component identifier as a structured field item.
+func (c componentID) item() structfield.Item {
+	it := structfield.Item{Value: c.name}
+	if c.key != "" {
+		it.Params = append(it.Params, structfield.Param{Key: "key", Value: c.key})
+	}
+	if c.req {
+		it.Params = append(it.Params, structfield.Param{Key: "req", Value: true})
+	}
+	return it
+}
+
+// String returns the serialized component identifier.
+func (c componentID) String() string {
+	s, err := c.item().Serialize()
+	if err != nil {
+		return fmt.Sprintf("%q", c.name)
+	}
+	return s
+}
+
+// parseComponentID parses a component identifier of the Signature-Input field.
+func parseComponentID(it structfield.Item) (componentID, error) {
+	var c componentID
+	var ok bool
+	if c.name, ok = it.Value.(string); !ok {
+		return componentID{}, fmt.Errorf("component identifier %v is not a string", it.Value)
+	}
+	for _, p := range it.Params {
+		switch p.Key {
+		case "key":
+			c.key, ok = p.Value.(string)
+		case "req":
+			c.req, ok = p.Value.(bool)
+		default:
+			return componentID{}, fmt.Errorf("component %q: parameter %q is not supported", c.name, p.Key)
+		}
+		if !ok {
+			return componentID{}, fmt.Errorf("component %q: invalid %q parameter", c.name, p.Key)
+		}
+	}
+	return c, nil
+}
+
+// message is an HTTP request or response whose components are signed.
+type message struct {
+	// req is the request, or for a response, the request it answers.
+	req *http.Request
+	// status is the status code of a response; zero for a request.
+	status int
+	// header are the fields of the message.
+	header http.Header
+}
+
+func requestMessage(r *http.Request) message {
+	return message{req: r, header: r.Header}
+}
+
+func (m message) isResponse() bool { return m.status != 0 }
+
+// componentValue returns the value of the component of the message:
+// either a derived component (RFC 9421 Section 2.2) or an HTTP field (RFC 9421 Section 2.1).
+// Components with the req parameter are taken from the request a response answers.
+func componentValue(m message, id componentID) (string, error) {
+	if id.req {
+		if !m.isResponse() {
+			return "", fmt.Errorf("%w: req parameter is only valid for responses", errUnknownComponent)
+		}
+		m = requestMessage(m.req)
+	}
+	if id.key != "" {
+		if strings.HasPrefix(id.name, "@") {
+			return "", fmt.Errorf("%w: key parameter is only valid for fields", errUnknownComponent)
+		}
+		return dictionaryMemberValue(m.header, id.name, id.key)
+	}
+	switch c := id.name; {
+	case c == ComponentStatus:
+		if !m.isResponse() {
+			return "", fmt.Errorf("%w: %s is only valid for responses", errUnknownComponent, c)
+		}
+		return strconv.Itoa(m.status), nil
+	case !strings.HasPrefix(c, "@"):
+		return fieldValue(m.header, c)
+	case m.isResponse():
+		return "", fmt.Errorf("%w: %s is only valid for requests", errUnknownComponent, c)
+	default:
+		return requestComponentValue(m.req, c)
+	}
+}
+
+// requestComponentValue returns the value of the derived component of the request.
+func requestComponentValue(r *http.Request, c string) (string, error) {
+	switch c {
+	case ComponentMethod:
+		if r.Method == "" {
+			return http.MethodGet, nil
+		}
+		return r.Method, nil
+	case ComponentTargetURI:
+		return scheme(r) + "://" + authority(r) + requestURI(r), nil
+	case ComponentAuthority:
+		return authority(r), nil
+	case ComponentScheme:
+		return scheme(r), nil
+	case ComponentRequestTarget:
+		switch {
+		case r.Method == http.MethodConnect:
+			return authority(r), nil
+		case r.Method == http.MethodOptions && r.URL.Path == "*":
+			return "*", nil
+		default:
+			return requestURI(r), nil
+		}
+	case ComponentPath:
+		if p := r.URL.EscapedPath(); p != "" {
+			return p, nil
+		}
+		return "/", nil // See https://www.rfc-editor.org/rfc/rfc9110#section-4.2.3
+	case ComponentQuery:
+		return "?" + r.URL.RawQuery, nil
+	default:
+		return "", errUnknownComponent
+	}
+}
+
+// fieldValue returns the value of the HTTP field with the lowercased name:
+// the values of the field lines unfolded, trimmed and joined with ", " (RFC 9421 Section 2.1).
+func fieldValue(h http.Header, name string) (string, error) {
+	if name == "" || name != strings.ToLower(name) {
+		return "", fmt.Errorf("invalid field name %q", name)
+	}
+	values := h.Values(name)
+	if len(values) == 0 {
+		return "", errMissingField
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.Trim(unfold(v), " \t")
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// unfold replaces each obsolete line folding (RFC 9112 Section 5.2) in the field value with a single space.
+func unfold(v string) string {
+	if !strings.Contains(v, "\n") {
+		return v
+	}
+	lines := strings.Split(v, "\n")
+	for i, l := range lines {
+		l = strings.TrimRight(l, " \t\r")
+		if i > 0 {
+			l = strings.TrimLeft(l, " \t")
+		}
+		lines[i] = l
+	}
+	return strings.Join(lines, " ")
+}
+
+// dictionaryMemberValue returns the serialized value of the member with the key
+// of the dictionary field with the lowercased name.
+func dictionaryMemberValue(h http.Header, name, key string) (string, error) {
+	if _, err := fieldValue(h, name); err != nil {
+		return "", err
+	}
+	d, err := parseDictionaryField(h, name)
+	if err != nil {
+		return "", err
+	}
+	v, ok := d.Get(key)
+	if !ok {
+		return "", fmt.Errorf("%w: missing member %q", errMissingField, key)
+	}
+	switch v := v.(type) {
+	case structfield.Item:
+		return v.Serialize()
+	case structfield.InnerList:
+		return v.Serialize()
+	default:
+		return "", fmt.Errorf("invalid member %q", key)
+	}
+}
+
+// authority returns the normalized authority of the request target:
+// lowercased, and without the port if it is the default port of the scheme.
+func authority(r *http.Request) string {
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	host = strings.ToLower(host)
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if (port == "80" && scheme(r) == "http") || (port == "443" && scheme(r) == "https") {
+			if strings.Contains(h, ":") {
+				return "[" + h + "]"
+			}
+			return h
+		}
+	}
+	return host
+}
+
+// scheme returns the scheme of the request target.
+func scheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// requestURI returns the path and query of the request target in origin-form.
+func requestURI(r *http.Request) string {
+	return r.URL.RequestURI()
+}
@@ -0,0 +1,224 @@
+package httpsign
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComponentValue(t *testing.T) {
+	tests := []struct {
+		method    string
+		target    string
+		tls       bool
+		component string
+		value     string
+	}{
+		{"POST", "http://www.example.com/path?param=value", false, ComponentMethod, "POST"},
+		{"GET", "http://www.example.com/path?param=value", false, ComponentTargetURI, "http://www.example.com/path?param=value"},
+		{"GET", "/path?param=value", true, ComponentTargetURI, "https://example.com/path?param=value"},
+		{"GET", "http://www.EXAMPLE.com:80/path", false, ComponentAuthority, "www.example.com"},
+		{"GET", "http://www.example.com:8080/path", false, ComponentAuthority, "www.example.com:8080"},
+		{"GET", "https://www.example.com:443/path", false, ComponentAuthority, "www.example.com"},
+		{"GET", "http://[::1]:80/path", false, ComponentAuthority, "[::1]"},
+		{"GET", "http://www.example.com/path", false, ComponentScheme, "http"},
+		{"GET", "/path", true, ComponentScheme, "https"},
+		{"GET", "http://www.example.com/path?param=value", false, ComponentRequestTarget, "/path?param=value"},
+		{"GET", "http://www.example.com", false, ComponentRequestTarget, "/"},
+		{"OPTIONS", "*", false, ComponentRequestTarget, "*"},
+		{"CONNECT", "www.example.com:443", false, ComponentRequestTarget, "www.example.com:443"},
+		{"GET", "http://www.example.com/path/a%2Fb", false, ComponentPath, "/path/a%2Fb"},
+		{"GET", "http://www.example.com", false, ComponentPath, "/"},
+		{"GET", "http://www.example.com/path?param=value&foo=bar&baz=bat%2Dman", false, ComponentQuery, "?param=value&foo=bar&baz=bat%2Dman"},
+		{"GET", "http://www.example.com/path", false, ComponentQuery, "?"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		if !tt.tls {
+			r.TLS = nil
+		} else if r.TLS == nil {
+			r.TLS = &tls.ConnectionState{}
+		}
+		got, err := componentValue(requestMessage(r), componentID{name: tt.component})
+		if err != nil {
+			t.Errorf("componentValue(%s %s, %q) error: %v", tt.method, tt.target, tt.component, err)
+			continue
+		}
+		if got != tt.value {
+			t.Errorf("componentValue(%s %s, %q) = %q, want %q", tt.method, tt.target, tt.component, got, tt.value)
+		}
+	}
+}
+
+func TestHTTP_Components(t *testing.T) {
+	components := [][]string{
+		{ComponentMethod},
+		{ComponentMethod, ComponentAuthority, ComponentPath},
+		{ComponentTargetURI},
+		{ComponentScheme, ComponentRequestTarget},
+		{ComponentQuery, ComponentPath, ComponentMethod},
+		{},
+	}
+	m := NewMiddleware(stubVerifier{})
+	m.ErrorHandler = loggingErrorHandler(t)
+	s := httptest.NewServer(m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer s.Close()
+
+	for _, cs := range components {
+		tr := NewTransport(stubSigner{})
+		tr.Components = cs
+		c := http.Client{Transport: tr}
+		resp, err := c.Get(s.URL + "/p/h?k1=v1&k1=v2")
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Get() with components %q; code: %d, want %d", cs, resp.StatusCode, http.StatusOK)
+		}
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	h := http.Header{}
+	h.Add("Content-Type", " application/json ")
+	h.Add("Cache-Control", "max-age=60")
+	h.Add("Cache-Control", "  must-revalidate")
+	h.Add("X-Folded", "  a,\r\n\t b  ")
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"content-type", "application/json"},
+		{"cache-control", "max-age=60, must-revalidate"},
+		{"x-folded", "a, b"},
+	}
+	for _, tt := range tests {
+		got, err := fieldValue(h, tt.name)
+		if err != nil {
+			t.Errorf("fieldValue(%q) error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.value {
+			t.Errorf("fieldValue(%q) = %q, want %q", tt.name, got, tt.value)
+		}
+	}
+	for _, name := range []string{"Content-Type", "x-missing", ""} {
+		if _, err := fieldValue(h, name); err == nil {
+			t.Errorf("fieldValue(%q) error is nil", name)
+		}
+	}
+}
+
+func TestComponentValue_Response(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://example.com/foo", nil)
+	r.Header.Set("Signature", "sig1=:AAEC:, sig2=:AwQF:")
+	r.Header.Set("Example-Dict", " a=1,    b=2;x=1;y=2, c=(a   b   c)")
+	m := message{req: r, status: http.StatusOK, header: http.Header{"Example-Dict": {"a=3"}}}
+
+	tests := []struct {
+		id    componentID
+		value string
+	}{
+		{componentID{name: ComponentStatus}, "200"},
+		{componentID{name: ComponentMethod, req: true}, "POST"},
+		{componentID{name: ComponentPath, req: true}, "/foo"},
+		{componentID{name: "signature", key: "sig2", req: true}, ":AwQF:"},
+		{componentID{name: "example-dict", key: "a"}, "3"},
+		{componentID{name: "example-dict", key: "b", req: true}, "2;x=1;y=2"},
+		{componentID{name: "example-dict", key: "c", req: true}, "(a b c)"},
+	}
+	for _, tt := range tests {
+		got, err := componentValue(m, tt.id)
+		if err != nil {
+			t.Errorf("componentValue(%s) error: %v", tt.id, err)
+			continue
+		}
+		if got != tt.value {
+			t.Errorf("componentValue(%s) = %q, want %q", tt.id, got, tt.value)
+		}
+	}
+
+	for _, id := range []componentID{
+		{name: ComponentMethod},
+		{name: ComponentStatus, req: true},
+		{name: "signature", key: "sig3", req: true},
+		{name: ComponentMethod, key: "a", req: true},
+	} {
+		if _, err := componentValue(m, id); err == nil {
+			t.Errorf("componentValue(%s) error is nil", id)
+		}
+	}
+	if _, err := componentValue(requestMessage(r), componentID{name: ComponentMethod, req: true}); err == nil {
+		t.Errorf("componentValue(request, %q;req) error is nil", ComponentMethod)
+	}
+}
+
+func TestHTTP_Headers(t *testing.T) {
+	m := NewMiddleware(stubVerifier{})
+	m.ErrorHandler = loggingErrorHandler(t)
+	s := httptest.NewServer(m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer s.Close()
+
+	tests := []struct {
+		name   string
+		policy MissingHeaderPolicy
+		tamper bool
+		digest bool // whether Content-Digest of a request body is covered
+		err    bool
+		code   int
+	}{
+		{"covered", MissingHeaderFail, false, false, false, http.StatusOK},
+		{"tampered", MissingHeaderFail, true, false, false, http.StatusUnauthorized},
+		{"skip", MissingHeaderSkip, false, false, false, http.StatusOK},
+		{"fail", MissingHeaderFail, false, false, true, 0},
+		{"digest", MissingHeaderFail, false, true, false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			tr.Headers = []string{"Content-Type", "X-Tenant"}
+			method, body := "GET", io.Reader(nil)
+			if tt.digest {
+				tr.Headers = append(tr.Headers, "Content-Digest")
+				method, body = "POST", strings.NewReader("hello")
+			}
+			tr.MissingHeaders = tt.policy
+			tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if tt.tamper {
+					r.Header.Set("X-Tenant", "other")
+				}
+				return http.DefaultTransport.RoundTrip(r)
+			})
+			r, err := http.NewRequest(method, s.URL, body)
+			if err != nil {
+				t.Fatalf("NewRequest() error: %v", err)
+			}
+			r.Header.Add("Content-Type", "text/plain")
+			if tt.policy == MissingHeaderFail && !tt.err {
+				r.Header.Add("X-Tenant", " acme ")
+				r.Header.Add("X-Tenant", "example")
+			}
+
+			resp, err := tr.RoundTrip(r)
+			if tt.err {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("RoundTrip() error is nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RoundTrip() error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Errorf("code: %d, want %d", resp.StatusCode, tt.code)
+			}
+		})
+	}
+}
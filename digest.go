@@ -0,0 +1,134 @@
+package httpsign
+
+import (
+	"crypto"
+	_ "crypto/sha256" // for DigestSHA256
+	_ "crypto/sha512" // for DigestSHA512
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+const contentDigestHeader = "Content-Digest"
+
+var errDigestMismatch = errors.New("digest mismatch")
+
+// Digest algorithms for the Content-Digest field (RFC 9530 Section 5).
+const (
+	DigestSHA256 = "sha-256"
+	DigestSHA512 = "sha-512"
+)
+
+var digestHashes = map[string]crypto.Hash{
+	DigestSHA256: crypto.SHA256,
+	DigestSHA512: crypto.SHA512,
+}
+
+// contentDigest returns the Content-Digest field value of the content using the digest algorithm.
+func contentDigest(alg string, content []byte) (string, error) {
+	hash, ok := digestHashes[alg]
+	if !ok || !hash.Available() {
+		return "", fmt.Errorf("unsupported digest algorithm %q", alg)
+	}
+	h := hash.New()
+	_, _ = h.Write(content) // never returns an error
+	return structfield.Dictionary{{Key: alg, Value: structfield.Item{Value: h.Sum(nil)}}}.Serialize()
+}
+
+// verifyContentDigest verifies the Content-Digest field of h against the content.
+// Every digest with a supported algorithm must match, and at least one must be present.
+func verifyContentDigest(h http.Header, content []byte) error {
+	digests, err := parseContentDigest(h)
+	if err != nil {
+		return err
+	}
+	for _, d := range digests {
+		_, _ = d.hash.Write(content) // never returns an error
+	}
+	return checkDigests(digests)
+}
+
+// digest is a digest of the Content-Digest field with a supported algorithm.
+type digest struct {
+	alg  string
+	hash hash.Hash
+	want []byte
+}
+
+// parseContentDigest parses the digests with supported algorithms from the Content-Digest field of h.
+func parseContentDigest(h http.Header) ([]digest, error) {
+	members, err := parseDictionaryField(h, contentDigestHeader)
+	if err != nil {
+		return nil, err
+	}
+	var digests []digest
+	for _, m := range members {
+		hash, ok := digestHashes[m.Key]
+		if !ok || !hash.Available() {
+			continue
+		}
+		it, _ := m.Value.(structfield.Item)
+		want, ok := it.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%s digest is not a byte sequence", m.Key)
+		}
+		digests = append(digests, digest{alg: m.Key, hash: hash.New(), want: want})
+	}
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("no supported digest algorithm in %s field", contentDigestHeader)
+	}
+	return digests, nil
+}
+
+// checkDigests checks that the digests of the content written to the hashes match.
+func checkDigests(digests []digest) error {
+	for _, d := range digests {
+		if subtle.ConstantTimeCompare(d.hash.Sum(nil), d.want) != 1 {
+			return fmt.Errorf("%s %w", d.alg, errDigestMismatch)
+		}
+	}
+	return nil
+}
+
+// digestReader verifies the digests of the content read from the underlying reader.
+// At the end of the content, Read returns a verification error instead of io.EOF if a digest does not match.
+type digestReader struct {
+	rc      io.ReadCloser
+	digests []digest
+	err     error
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	n, err := d.rc.Read(p)
+	for _, dg := range d.digests {
+		_, _ = dg.hash.Write(p[:n]) // never returns an error
+	}
+	if err == io.EOF {
+		if derr := checkDigests(d.digests); derr != nil {
+			err = digestError(derr)
+		}
+	}
+	if err != nil {
+		d.err = err
+	}
+	return n, err
+}
+
+func (d *digestReader) Close() error { return d.rc.Close() }
+
+// readBody reads and closes the body, returning its content.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
@@ -0,0 +1,118 @@
+package httpsign
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContentDigest(t *testing.T) {
+	content := []byte(`{"hello": "world"}` + "\n")
+	tests := []struct {
+		alg    string
+		digest string
+	}{
+		{DigestSHA256, "sha-256=:RK/0qy18MlBSVnWgjwz6lZEWjP/lF5HF9bvEF8FabDg=:"},
+		{DigestSHA512, "sha-512=:YMAam51Jz/jOATT6/zvHrLVgOYTGFy1d6GJiOHTohq4yP+pgk4vf2aCsyRZOtw8MjkM7iw7yZ/WkppmM44T3qg==:"},
+	}
+	for _, tt := range tests {
+		got, err := contentDigest(tt.alg, content)
+		if err != nil {
+			t.Fatalf("contentDigest(%q) error: %v", tt.alg, err)
+		}
+		if got != tt.digest {
+			t.Errorf("contentDigest(%q) = %q, want %q", tt.alg, got, tt.digest)
+		}
+	}
+	if _, err := contentDigest("md5", content); err == nil {
+		t.Errorf("contentDigest(%q) error is nil", "md5")
+	}
+}
+
+func TestVerifyContentDigest(t *testing.T) {
+	content := []byte(`{"hello": "world"}` + "\n")
+	tests := []struct {
+		digest string
+		valid  bool
+	}{
+		{"sha-256=:RK/0qy18MlBSVnWgjwz6lZEWjP/lF5HF9bvEF8FabDg=:", true},
+		{"sha-256=:RK/0qy18MlBSVnWgjwz6lZEWjP/lF5HF9bvEF8FabDg=:, md5=:AAAA:", true},
+		{"sha-256=:RK/0qy18MlBSVnWgjwz6lZEWjP/lF5HF9bvEF8FabDg=:, sha-512=:AAAA:", false},
+		{"sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", false},
+		{"sha-256=\"RK/0qy18MlBSVnWgjwz6lZEWjP/lF5HF9bvEF8FabDg=\"", false},
+		{"md5=:AAAA:", false},
+		{"sha-256", false},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set(contentDigestHeader, tt.digest)
+		err := verifyContentDigest(h, content)
+		if tt.valid && err != nil {
+			t.Errorf("verifyContentDigest(%q) error: %v", tt.digest, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("verifyContentDigest(%q) error is nil", tt.digest)
+		}
+	}
+}
+
+func TestHTTP_ContentDigest(t *testing.T) {
+	body := []byte("test request body")
+	m := NewMiddleware(stubVerifier{})
+	m.ErrorHandler = loggingErrorHandler(t)
+	m.MaxBodySize = int64(len(body))
+	s := httptest.NewServer(m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("ReadAll() error: %v", err)
+		}
+		if !bytes.Equal(got, body) {
+			t.Errorf("Handler body = %q, want %q", got, body)
+		}
+	})))
+	defer s.Close()
+
+	tests := []struct {
+		name   string
+		alg    string
+		tamper func(r *http.Request)
+		code   int
+	}{
+		{"sha-256", DigestSHA256, nil, http.StatusOK},
+		{"sha-512", DigestSHA512, nil, http.StatusOK},
+		{"tampered body", DigestSHA256, func(r *http.Request) {
+			r.Body = io.NopCloser(bytes.NewReader([]byte("forged reqst body")))
+		}, http.StatusUnauthorized},
+		{"removed digest", DigestSHA256, func(r *http.Request) {
+			r.Header.Del(contentDigestHeader)
+		}, http.StatusUnauthorized},
+		{"too large", DigestSHA256, func(r *http.Request) {
+			large := append(bytes.Clone(body), '!')
+			r.Body = io.NopCloser(bytes.NewReader(large))
+			r.ContentLength = int64(len(large))
+		}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			tr.DigestAlgorithm = tt.alg
+			tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if tt.tamper != nil {
+					tt.tamper(r)
+				}
+				return http.DefaultTransport.RoundTrip(r)
+			})
+			c := http.Client{Transport: tr}
+			resp, err := c.Post(s.URL, "text/plain", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("Post() error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Errorf("Post(); code: %d, want %d", resp.StatusCode, tt.code)
+			}
+		})
+	}
+}
@@ -1,16 +1,29 @@
 // Package ecdsa provides utilities for signing and verifying messages using ECDSA.
+//
+// Signatures are encoded as the fixed-size concatenation of the big-endian r and s values,
+// as required by RFC 9421 Section 3.3.4, rather than as ASN.1 DER.
 package ecdsa
 
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/asn1"
 	"errors"
+	"fmt"
 	"io"
+	"math/big"
+
+	"github.com/denpeshkov/httpsign"
 )
 
-// ErrHashUnavailable is returned when the hash function is not linked into the binary.
-var ErrHashUnavailable = errors.New("ecdsa: requested hash function is unavailable")
+var (
+	// ErrHashUnavailable is returned when the hash function is not linked into the binary.
+	ErrHashUnavailable = errors.New("ecdsa: requested hash function is unavailable")
+	// ErrUnsupportedKey is returned when the public key of a [crypto.Signer] is not an ECDSA key.
+	ErrUnsupportedKey = errors.New("ecdsa: public key is not an ECDSA key")
+)
 
 // Signer signs messages using ECDSA.
 // It is safe for concurrent use by multiple goroutines.
@@ -18,24 +31,50 @@ type Signer struct {
 	Verifier
 	Rand io.Reader // Defaults to crypto/rand.Reader if not set.
 
-	priv *ecdsa.PrivateKey
+	priv crypto.Signer
 }
 
 // NewSigner returns a new [Signer] for the provided private key and hash algorithm.
 func NewSigner(priv *ecdsa.PrivateKey, hash crypto.Hash) (*Signer, error) {
+	return NewCryptoSigner(priv, hash)
+}
+
+// NewCryptoSigner returns a new [Signer] for the provided [crypto.Signer] and hash algorithm.
+// The public key of the signer must be an *ecdsa.PublicKey, and the signer must return
+// ASN.1 DER signatures, as *ecdsa.PrivateKey does; they are converted to the RFC 9421 encoding.
+func NewCryptoSigner(signer crypto.Signer, hash crypto.Hash) (*Signer, error) {
 	if !hash.Available() {
 		return nil, ErrHashUnavailable
 	}
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, signer.Public())
+	}
 	return &Signer{
 		Rand:     rand.Reader,
-		priv:     priv,
-		Verifier: Verifier{pub: &priv.PublicKey, hash: hash},
+		priv:     signer,
+		Verifier: Verifier{pub: pub, hash: hash},
 	}, nil
 }
 
 // Sign signs a message using the private key.
 func (s *Signer) Sign(message []byte) ([]byte, error) {
-	return ecdsa.SignASN1(s.Rand, s.priv, s.digest(message))
+	der, err := s.priv.Sign(s.Rand, s.digest(message), s.hash)
+	if err != nil {
+		return nil, err
+	}
+	var rs struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &rs); err != nil || len(rest) > 0 {
+		return nil, errors.New("ecdsa: invalid ASN.1 signature")
+	}
+	size := s.size()
+	if rs.R.Sign() <= 0 || rs.S.Sign() <= 0 || rs.R.BitLen() > 8*size || rs.S.BitLen() > 8*size {
+		return nil, errors.New("ecdsa: invalid signature values")
+	}
+	sig := make([]byte, 2*size)
+	rs.R.FillBytes(sig[:size])
+	rs.S.FillBytes(sig[size:])
+	return sig, nil
 }
 
 // Verifier verifies ECDSA message signatures.
@@ -55,7 +94,36 @@ func NewVerifier(pub *ecdsa.PublicKey, hash crypto.Hash) (*Verifier, error) {
 
 // Verify verifies the signature of a message using the public key.
 func (v *Verifier) Verify(message []byte, signature []byte) (bool, error) {
-	return ecdsa.VerifyASN1(v.pub, v.digest(message), signature), nil
+	size := v.size()
+	if len(signature) != 2*size {
+		return false, fmt.Errorf("ecdsa: signature length %d, want %d: %w", len(signature), 2*size, httpsign.ErrMalformedSignature)
+	}
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	return ecdsa.Verify(v.pub, v.digest(message), r, s), nil
+}
+
+// Algorithm returns the HTTP Signature Algorithms registry name of the algorithm,
+// or an empty string if the curve and hash algorithm have no registered name.
+func (v *Verifier) Algorithm() string {
+	switch {
+	case v.pub.Curve == elliptic.P256() && v.hash == crypto.SHA256:
+		return httpsign.AlgorithmECDSAP256SHA256
+	case v.pub.Curve == elliptic.P384() && v.hash == crypto.SHA384:
+		return httpsign.AlgorithmECDSAP384SHA384
+	default:
+		return ""
+	}
+}
+
+// Public returns the public key.
+func (v *Verifier) Public() crypto.PublicKey {
+	return v.pub
+}
+
+// size returns the size in bytes of each of the r and s signature values.
+func (v *Verifier) size() int {
+	return (v.pub.Curve.Params().N.BitLen() + 7) / 8
 }
 
 func (v *Verifier) digest(msg []byte) []byte {
@@ -5,8 +5,12 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	_ "crypto/sha256"
+	"errors"
 	"testing"
+
+	"github.com/denpeshkov/httpsign"
 )
 
 func TestSignVerify(t *testing.T) {
@@ -57,3 +61,75 @@ func TestSignVerify(t *testing.T) {
 		testf()
 	})
 }
+
+func TestVerify_Invalid(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	sig, err := NewSigner(key, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("NewSigner() error: %v", err)
+	}
+	msg := []byte("test")
+	sign, err := sig.Sign(msg)
+	if err != nil {
+		t.Fatalf("Sign(%s) error: %v", msg, err)
+	}
+	if len(sign) != 64 {
+		t.Errorf("Sign(%s) signature length = %d, want %d", msg, len(sign), 64)
+	}
+
+	if ok, err := sig.Verify([]byte("forged"), sign); ok || err != nil {
+		t.Errorf("Verify(forged, %x) = %t, %v; want false, nil", sign, ok, err)
+	}
+	if ok, err := sig.Verify(msg, make([]byte, 64)); ok || err != nil {
+		t.Errorf("Verify(%s, zero) = %t, %v; want false, nil", msg, ok, err)
+	}
+	if ok, err := sig.Verify(msg, sign[1:]); ok || !errors.Is(err, httpsign.ErrMalformedSignature) {
+		t.Errorf("Verify(%s, %x) = %t, %v; want false, %v", msg, sign[1:], ok, err, httpsign.ErrMalformedSignature)
+	}
+}
+
+// opaqueSigner is a crypto.Signer without access to its private key.
+type opaqueSigner struct{ crypto.Signer }
+
+func TestCryptoSigner(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error: %v", err)
+		}
+		sig, err := NewCryptoSigner(opaqueSigner{key}, crypto.SHA256)
+		if err != nil {
+			t.Fatalf("NewCryptoSigner() error: %v", err)
+		}
+		ver, err := NewVerifier(&key.PublicKey, crypto.SHA256)
+		if err != nil {
+			t.Fatalf("NewVerifier() error: %v", err)
+		}
+
+		msg := []byte("test")
+		sign, err := sig.Sign(msg)
+		if err != nil {
+			t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+		}
+		if want := 2 * ((curve.Params().N.BitLen() + 7) / 8); len(sign) != want {
+			t.Errorf("%s signature length %d, want %d", curve.Params().Name, len(sign), want)
+		}
+		if ok, err := ver.Verify(msg, sign); err != nil || !ok {
+			t.Errorf("%s signed message not verified by Verifier: %v", curve.Params().Name, err)
+		}
+		if pub := sig.Public(); !key.PublicKey.Equal(pub) {
+			t.Errorf("%s Signer.Public() = %v, want %v", curve.Params().Name, pub, &key.PublicKey)
+		}
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	if _, err := NewCryptoSigner(key, crypto.SHA256); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("NewCryptoSigner(RSA key) error = %v, want %v", err, ErrUnsupportedKey)
+	}
+}
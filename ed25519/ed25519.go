@@ -2,18 +2,27 @@
 package ed25519
 
 import (
+	"crypto"
 	"crypto/ed25519"
+	"crypto/rand"
 	"errors"
+	"fmt"
+
+	"github.com/denpeshkov/httpsign"
 )
 
-var ErrInvalidKey = errors.New("ed25519: bad key length")
+var (
+	ErrInvalidKey = errors.New("ed25519: bad key length")
+	// ErrUnsupportedKey is returned when the public key of a [crypto.Signer] is not an Ed25519 key.
+	ErrUnsupportedKey = errors.New("ed25519: public key is not an Ed25519 key")
+)
 
 // Signer signs messages using Ed25519.
 // It is safe for concurrent use by multiple goroutines.
 type Signer struct {
 	Verifier
 
-	priv ed25519.PrivateKey
+	priv crypto.Signer
 }
 
 // NewSigner returns a new [Signer] for the provided private key and hash algorithm.
@@ -21,15 +30,28 @@ func NewSigner(priv ed25519.PrivateKey) (*Signer, error) {
 	if len(priv) != ed25519.PrivateKeySize {
 		return nil, ErrInvalidKey
 	}
+	return NewCryptoSigner(priv)
+}
+
+// NewCryptoSigner returns a new [Signer] for the provided [crypto.Signer].
+// The public key of the signer must be an ed25519.PublicKey.
+func NewCryptoSigner(signer crypto.Signer) (*Signer, error) {
+	pub, ok := signer.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, signer.Public())
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
 	return &Signer{
-		priv:     priv,
-		Verifier: Verifier{pub: priv.Public().(ed25519.PublicKey)},
+		priv:     signer,
+		Verifier: Verifier{pub: pub},
 	}, nil
 }
 
 // Sign signs a message using the private key.
 func (s *Signer) Sign(message []byte) ([]byte, error) {
-	return ed25519.Sign(s.priv, message), nil
+	return s.priv.Sign(rand.Reader, message, crypto.Hash(0))
 }
 
 // Verifier verifies Ed25519 message signatures.
@@ -48,5 +70,18 @@ func NewVerifier(pub ed25519.PublicKey) (*Verifier, error) {
 
 // Verify verifies the signature of a message using the public key.
 func (v *Verifier) Verify(message []byte, signature []byte) (bool, error) {
+	if len(signature) != ed25519.SignatureSize {
+		return false, fmt.Errorf("ed25519: signature length %d, want %d: %w", len(signature), ed25519.SignatureSize, httpsign.ErrMalformedSignature)
+	}
 	return ed25519.Verify(v.pub, message, signature), nil
 }
+
+// Algorithm returns the HTTP Signature Algorithms registry name of the algorithm.
+func (v *Verifier) Algorithm() string {
+	return httpsign.AlgorithmEd25519
+}
+
+// Public returns the public key.
+func (v *Verifier) Public() crypto.PublicKey {
+	return v.pub
+}
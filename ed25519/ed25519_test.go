@@ -1,9 +1,15 @@
 package ed25519
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"errors"
 	"testing"
+
+	"github.com/denpeshkov/httpsign"
 )
 
 func TestSignVerify(t *testing.T) {
@@ -53,3 +59,67 @@ func TestSignVerify(t *testing.T) {
 		testf()
 	})
 }
+
+func TestVerify_Invalid(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	sig, err := NewSigner(priv)
+	if err != nil {
+		t.Fatalf("NewSigner() error: %v", err)
+	}
+	msg := []byte("test")
+	sign, err := sig.Sign(msg)
+	if err != nil {
+		t.Fatalf("Sign(%s) error: %v", msg, err)
+	}
+
+	if ok, err := sig.Verify([]byte("forged"), sign); ok || err != nil {
+		t.Errorf("Verify(forged, %x) = %t, %v; want false, nil", sign, ok, err)
+	}
+	if ok, err := sig.Verify(msg, sign[1:]); ok || !errors.Is(err, httpsign.ErrMalformedSignature) {
+		t.Errorf("Verify(%s, %x) = %t, %v; want false, %v", msg, sign[1:], ok, err, httpsign.ErrMalformedSignature)
+	}
+}
+
+// opaqueSigner is a crypto.Signer without access to its private key.
+type opaqueSigner struct{ crypto.Signer }
+
+func TestCryptoSigner(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	sig, err := NewCryptoSigner(opaqueSigner{priv})
+	if err != nil {
+		t.Fatalf("NewCryptoSigner() error: %v", err)
+	}
+	ver, err := NewVerifier(pub)
+	if err != nil {
+		t.Fatalf("NewVerifier() error: %v", err)
+	}
+
+	msg := []byte("test")
+	sign, err := sig.Sign(msg)
+	if err != nil {
+		t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+	}
+	if want := ed25519.Sign(priv, msg); string(sign) != string(want) {
+		t.Errorf("Signer.Sign(%s) = %x, want %x", msg, sign, want)
+	}
+	if ok, err := ver.Verify(msg, sign); err != nil || !ok {
+		t.Errorf("Signed message not verified by Verifier: %v", err)
+	}
+	if got := sig.Public(); !pub.Equal(got) {
+		t.Errorf("Signer.Public() = %x, want %x", got, pub)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	if _, err := NewCryptoSigner(key); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("NewCryptoSigner(ECDSA key) error = %v, want %v", err, ErrUnsupportedKey)
+	}
+}
@@ -0,0 +1,125 @@
+package httpsign
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrVerification represents a failure to verify a signature.
+	ErrVerification = errors.New("signature verification error")
+	// ErrStaleSignature is returned when a signature was created longer ago than the maximum age.
+	// It wraps ErrVerification.
+	ErrStaleSignature = fmt.Errorf("%w: signature is too old", ErrVerification)
+	// ErrExpiredSignature is returned when the expires parameter of a signature is in the past.
+	// It wraps ErrVerification.
+	ErrExpiredSignature = fmt.Errorf("%w: signature has expired", ErrVerification)
+	// ErrFutureSignature is returned when a signature was created in the future beyond the allowed clock skew.
+	// It wraps ErrVerification.
+	ErrFutureSignature = fmt.Errorf("%w: signature is created in the future", ErrVerification)
+	// ErrReplayedSignature is returned when the nonce of a signature was already used.
+	// It wraps ErrVerification.
+	ErrReplayedSignature = fmt.Errorf("%w: signature nonce was already used", ErrVerification)
+)
+
+// Reason is the reason of a signature verification failure.
+type Reason int
+
+// Reasons of signature verification failures.
+const (
+	// ReasonMissingHeader means the message has no Signature-Input or Signature field,
+	// or no member for a signature label.
+	ReasonMissingHeader Reason = iota + 1
+	// ReasonMalformedHeader means a signature field or its parameters cannot be parsed.
+	ReasonMalformedHeader
+	// ReasonMissingParameter means a required signature parameter is absent.
+	ReasonMissingParameter
+	// ReasonMissingComponent means a covered component is absent from the message.
+	ReasonMissingComponent
+	// ReasonUnsupportedComponent means a covered component is unknown or invalid.
+	ReasonUnsupportedComponent
+	// ReasonExpired means the signature is too old or its expires parameter is in the past.
+	ReasonExpired
+	// ReasonNotYetValid means the signature is created in the future.
+	ReasonNotYetValid
+	// ReasonReplayed means the nonce of the signature was already used.
+	ReasonReplayed
+	// ReasonUnknownKey means the key identified by the signature is unknown.
+	ReasonUnknownKey
+	// ReasonAlgorithmMismatch means the alg parameter of the signature does not match the key.
+	ReasonAlgorithmMismatch
+	// ReasonMalformedSignature means the signature cannot be a valid signature for the key.
+	ReasonMalformedSignature
+	// ReasonSignatureMismatch means the signature does not match the message.
+	ReasonSignatureMismatch
+	// ReasonDigestMismatch means the Content-Digest field does not match the content.
+	ReasonDigestMismatch
+	// ReasonUntrustedKey means the key identified by the signature is not trusted.
+	ReasonUntrustedKey
+)
+
+var reasons = map[Reason]string{
+	ReasonMissingHeader:        "missing header",
+	ReasonMalformedHeader:      "malformed header",
+	ReasonMissingParameter:     "missing parameter",
+	ReasonMissingComponent:     "missing component",
+	ReasonUnsupportedComponent: "unsupported component",
+	ReasonExpired:              "expired",
+	ReasonNotYetValid:          "not yet valid",
+	ReasonReplayed:             "replayed",
+	ReasonUnknownKey:           "unknown key",
+	ReasonAlgorithmMismatch:    "algorithm mismatch",
+	ReasonMalformedSignature:   "malformed signature",
+	ReasonSignatureMismatch:    "signature mismatch",
+	ReasonDigestMismatch:       "digest mismatch",
+	ReasonUntrustedKey:         "untrusted key",
+}
+
+// String returns a short description of the reason.
+func (r Reason) String() string {
+	if s, ok := reasons[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("Reason(%d)", int(r))
+}
+
+// VerificationError describes a signature verification failure.
+// It matches [ErrVerification] with [errors.Is].
+type VerificationError struct {
+	Reason Reason
+	// Label is the label of the failing signature, if known.
+	Label string
+	// KeyID is the keyid parameter of the failing signature, if known.
+	KeyID string
+	// Component is the identifier of the failing component, if any.
+	Component string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *VerificationError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrVerification.Error())
+	if e.Label != "" {
+		fmt.Fprintf(&b, ": signature %q", e.Label)
+	}
+	if e.KeyID != "" {
+		fmt.Fprintf(&b, ": key %q", e.KeyID)
+	}
+	if e.Component != "" {
+		fmt.Fprintf(&b, ": component %s", e.Component)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Reason.String())
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(strings.TrimPrefix(e.Err.Error(), ErrVerification.Error()+": "))
+	}
+	return b.String()
+}
+
+func (e *VerificationError) Unwrap() error { return e.Err }
+
+// Is reports whether the target is ErrVerification.
+func (e *VerificationError) Is(target error) bool { return target == ErrVerification }
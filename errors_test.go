@@ -0,0 +1,198 @@
+package httpsign
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerificationError(t *testing.T) {
+	err := error(&VerificationError{Reason: ReasonExpired, Label: "sig1", KeyID: "k", Err: ErrStaleSignature})
+	if !errors.Is(err, ErrVerification) {
+		t.Errorf("errors.Is(%v, ErrVerification) = false", err)
+	}
+	if !errors.Is(err, ErrStaleSignature) {
+		t.Errorf("errors.Is(%v, ErrStaleSignature) = false", err)
+	}
+	want := `signature verification error: signature "sig1": key "k": expired: signature is too old`
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if s := Reason(100).String(); s != "Reason(100)" {
+		t.Errorf("String() = %q, want %q", s, "Reason(100)")
+	}
+}
+
+// algVerifier is a stubVerifier with a registered algorithm name.
+type algVerifier struct {
+	stubVerifier
+	alg string
+}
+
+func (v algVerifier) Algorithm() string { return v.alg }
+
+// algSigner is a stubSigner with a registered algorithm name.
+type algSigner struct {
+	stubSigner
+	alg string
+}
+
+func (s algSigner) Algorithm() string { return s.alg }
+
+// usedNonceStore is a NonceStore which reports every nonce as already used.
+type usedNonceStore struct{}
+
+func (usedNonceStore) Add(context.Context, string, time.Time) (bool, error) { return false, nil }
+
+func TestMiddleware_VerificationError(t *testing.T) {
+	tests := []struct {
+		name       string
+		transport  func(tr *Transport)
+		tamper     func(r *http.Request)
+		middleware func(m *Middleware)
+		reason     Reason
+		label      string
+		keyID      string
+		component  string
+	}{
+		{
+			name:   "missing header",
+			tamper: func(r *http.Request) { r.Header.Del(signatureInputHeader) },
+			reason: ReasonMissingHeader,
+		},
+		{
+			name:   "malformed header",
+			tamper: func(r *http.Request) { r.Header.Set(signatureHeader, "sig1=:not base64!:") },
+			reason: ReasonMalformedHeader,
+		},
+		{
+			name:   "missing signature member",
+			tamper: func(r *http.Request) { r.Header.Set(signatureHeader, "sig2=:AAAA:") },
+			reason: ReasonMissingHeader,
+			label:  "sig1",
+		},
+		{
+			name:   "missing created",
+			tamper: func(r *http.Request) { r.Header.Set(signatureInputHeader, `sig1=("@method")`) },
+			reason: ReasonMissingParameter,
+			label:  "sig1",
+		},
+		{
+			name:       "expired",
+			transport:  func(tr *Transport) { tr.KeyID = "k" },
+			middleware: func(m *Middleware) { m.Now = func() time.Time { return time.Now().Add(time.Hour) } },
+			reason:     ReasonExpired,
+			label:      "sig1",
+			keyID:      "k",
+		},
+		{
+			name:       "not yet valid",
+			middleware: func(m *Middleware) { m.Now = func() time.Time { return time.Now().Add(-time.Hour) } },
+			reason:     ReasonNotYetValid,
+			label:      "sig1",
+		},
+		{
+			name:       "unknown key",
+			transport:  func(tr *Transport) { tr.KeyID = "unknown" },
+			middleware: func(m *Middleware) { m.resolver = Keys{"k": stubVerifier{}} },
+			reason:     ReasonUnknownKey,
+			label:      "sig1",
+			keyID:      "unknown",
+		},
+		{
+			name:       "algorithm mismatch",
+			transport:  func(tr *Transport) { tr.signer = algSigner{alg: "hmac-sha256"} },
+			middleware: func(m *Middleware) { m.resolver = singleKey{algVerifier{alg: "ed25519"}} },
+			reason:     ReasonAlgorithmMismatch,
+			label:      "sig1",
+		},
+		{
+			name:   "signature mismatch",
+			tamper: func(r *http.Request) { r.URL.Path = "/forged" },
+			reason: ReasonSignatureMismatch,
+			label:  "sig1",
+		},
+		{
+			name:       "malformed signature",
+			middleware: func(m *Middleware) { m.resolver = singleKey{errVerifier{ErrMalformedSignature}} },
+			reason:     ReasonMalformedSignature,
+			label:      "sig1",
+		},
+		{
+			name:      "missing component",
+			transport: func(tr *Transport) { tr.Components = []string{"x-tenant"} },
+			tamper:    func(r *http.Request) { r.Header.Del("X-Tenant") },
+			reason:    ReasonMissingComponent,
+			label:     "sig1",
+			component: `"x-tenant"`,
+		},
+		{
+			name: "unsupported component",
+			tamper: func(r *http.Request) {
+				r.Header.Set(signatureInputHeader, strings.Replace(r.Header.Get(signatureInputHeader), "@method", "@unknown", 1))
+			},
+			reason:    ReasonUnsupportedComponent,
+			label:     "sig1",
+			component: `"@unknown"`,
+		},
+		{
+			name:      "digest mismatch",
+			tamper:    func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader("forged")) },
+			reason:    ReasonDigestMismatch,
+			component: `"content-digest"`,
+		},
+		{
+			name:       "untrusted key",
+			middleware: func(m *Middleware) { m.resolver = singleKey{errVerifier{ErrUntrustedKey}} },
+			reason:     ReasonUntrustedKey,
+			label:      "sig1",
+		},
+		{
+			name:       "replayed",
+			middleware: func(m *Middleware) { m.NonceStore = usedNonceStore{} },
+			reason:     ReasonReplayed,
+			label:      "sig1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			if tt.transport != nil {
+				tt.transport(tr)
+			}
+			r := httptest.NewRequest("POST", "http://example.com/", bytes.NewReader([]byte("body")))
+			r.Header.Set("X-Tenant", "tenant")
+			r = signedRequest(t, tr, r)
+			if tt.tamper != nil {
+				tt.tamper(r)
+			}
+			m := NewMiddleware(stubVerifier{})
+			if tt.middleware != nil {
+				tt.middleware(m)
+			}
+
+			var verr *VerificationError
+			if err := serve(m, r); !errors.As(err, &verr) {
+				t.Fatalf("Handler() error = %v, want VerificationError", err)
+			}
+			if verr.Reason != tt.reason {
+				t.Errorf("Reason = %v, want %v (error: %v)", verr.Reason, tt.reason, verr)
+			}
+			if verr.Label != tt.label {
+				t.Errorf("Label = %q, want %q", verr.Label, tt.label)
+			}
+			if verr.KeyID != tt.keyID {
+				t.Errorf("KeyID = %q, want %q", verr.KeyID, tt.keyID)
+			}
+			if verr.Component != tt.component {
+				t.Errorf("Component = %q, want %q", verr.Component, tt.component)
+			}
+		})
+	}
+}
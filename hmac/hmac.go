@@ -5,6 +5,9 @@ import (
 	"crypto"
 	"crypto/hmac"
 	"errors"
+	"fmt"
+
+	"github.com/denpeshkov/httpsign"
 )
 
 // ErrHashUnavailable is returned when the hash function is not linked into the binary.
@@ -32,9 +35,21 @@ func (h HMAC) Sign(message []byte) ([]byte, error) {
 
 // Verify verifies the signature of a message using the key.
 func (h HMAC) Verify(message []byte, signature []byte) (bool, error) {
+	if len(signature) != h.hash.Size() {
+		return false, fmt.Errorf("hmac: signature length %d, want %d: %w", len(signature), h.hash.Size(), httpsign.ErrMalformedSignature)
+	}
 	return hmac.Equal(signature, h.digest(message)), nil
 }
 
+// Algorithm returns the HTTP Signature Algorithms registry name of the algorithm,
+// or an empty string if the hash algorithm has no registered name.
+func (h HMAC) Algorithm() string {
+	if h.hash == crypto.SHA256 {
+		return httpsign.AlgorithmHMACSHA256
+	}
+	return ""
+}
+
 func (h HMAC) digest(msg []byte) []byte {
 	hash := hmac.New(h.hash.New, h.key)
 	_, _ = hash.Write(msg) // never returns an error
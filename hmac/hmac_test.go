@@ -3,7 +3,10 @@ package hmac
 import (
 	"crypto"
 	_ "crypto/sha256"
+	"errors"
 	"testing"
+
+	"github.com/denpeshkov/httpsign"
 )
 
 func TestSignVerify(t *testing.T) {
@@ -40,3 +43,22 @@ func TestSignVerify(t *testing.T) {
 		testf()
 	})
 }
+
+func TestVerify_Invalid(t *testing.T) {
+	sig, err := New([]byte("secret"), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	msg := []byte("test")
+	sign, err := sig.Sign(msg)
+	if err != nil {
+		t.Fatalf("Sign(%s) error: %v", msg, err)
+	}
+
+	if ok, err := sig.Verify([]byte("forged"), sign); ok || err != nil {
+		t.Errorf("Verify(forged, %x) = %t, %v; want false, nil", sign, ok, err)
+	}
+	if ok, err := sig.Verify(msg, sign[1:]); ok || !errors.Is(err, httpsign.ErrMalformedSignature) {
+		t.Errorf("Verify(%s, %x) = %t, %v; want false, %v", msg, sign[1:], ok, err, httpsign.ErrMalformedSignature)
+	}
+}
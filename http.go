@@ -1,32 +1,95 @@
-// Package httpsign provides utilities for signing and verifying HTTP requests.
+// Package httpsign provides utilities for signing and verifying HTTP requests
+// using HTTP Message Signatures (RFC 9421).
 package httpsign
 
 import (
-	"encoding/base64"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"net/url"
 	"slices"
 	"strings"
 	"time"
+
+	"github.com/denpeshkov/httpsign/structfield"
 )
 
 const (
-	signatureHeader = "X-Signature"
-	timestampHeader = "X-Signature-Timestamp"
+	signatureInputHeader = "Signature-Input"
+	signatureHeader      = "Signature"
 )
 
-var (
-	// ErrVerification represents a failure to verify a signature.
-	ErrVerification = errors.New("signature verification error")
+// DefaultLabel is the label of the signatures created by [Transport] and [ResponseSigner] by default.
+const DefaultLabel = "sig1"
+
+// Default freshness limits of a [Middleware] and of the response signatures verified by a [Transport].
+const (
+	DefaultMaxAge  = 5 * time.Minute
+	DefaultMaxSkew = time.Minute
 )
 
+// DefaultMaxBodySize is the default size limit of request bodies read by a [Middleware] to verify their digest.
+const DefaultMaxBodySize = 10 << 20
+
 // Transport is an HTTP [http.RoundTripper] which signs outgoing HTTP requests.
+// The signature is sent in the Signature-Input and Signature fields as specified in RFC 9421.
 type Transport struct {
 	// Base is the base http.RoundTripper used to make HTTP requests.
 	// By default, http.DefaultTransport is used.
 	Base http.RoundTripper
+	// Components are the derived components (RFC 9421 Section 2.2) covered by the signature,
+	// in order. By default, DefaultComponents are covered.
+	Components []string
+	// Headers are the names of the request header fields covered by the signature, in order, after Components.
+	// Fields set by the base http.RoundTripper, such as Host and Content-Length, cannot be covered.
+	Headers []string
+	// MissingHeaders is the policy for Headers missing from a request.
+	// By default, signing a request without any of them fails.
+	MissingHeaders MissingHeaderPolicy
+	// DigestAlgorithm is the algorithm used to compute the Content-Digest field (RFC 9530) of requests with a body,
+	// which is then covered by the signature. By default, DigestSHA256 is used.
+	// If empty, the request body is not signed.
+	DigestAlgorithm string
+	// Nonce returns the nonce sent as the nonce signature parameter to protect against replay.
+	// If nil, the parameter is omitted. By default, RandomNonce is used.
+	Nonce func() (string, error)
+	// KeyID is sent as the keyid signature parameter to identify the key used to sign requests.
+	// If empty, the parameter is omitted.
+	KeyID string
+	// TTL is the lifetime of a signature, sent as the expires signature parameter relative to created.
+	// Signatures are rejected by a Middleware once expired, regardless of its MaxAge.
+	// If zero, the parameter is omitted.
+	TTL time.Duration
+	// Label is the label of the signature in the Signature-Input and Signature fields.
+	// Signatures already present in the request are kept, so that Transports can be chained
+	// to sign a request with several keys, each with its own label. By default, DefaultLabel is used.
+	Label string
+	// ResponseResolver resolves the Verifier of response signatures.
+	// If not nil, every signature of a response must be valid, otherwise RoundTrip returns
+	// an error wrapping ErrVerification. If the response has a Content-Digest field,
+	// the body is verified as it is read, and reading the end of the body fails with
+	// such an error if the digest does not match. By default, responses are not verified.
+	ResponseResolver KeyResolver
+	// RequireBoundResponse reports whether response signatures must cover every signature of the request
+	// with the req parameter (RFC 9421 Section 2.4), proving that the response was issued for the request.
+	// It has no effect if ResponseResolver is nil. See ResponseSigner.RequestSignatures.
+	RequireBoundResponse bool
+	// ResponseMaxAge is the maximum age of a response signature, measured from its created parameter.
+	// If zero, the age is not limited and response signatures without a created parameter are accepted.
+	// Response signatures with an expires parameter in the past are rejected regardless of ResponseMaxAge.
+	// By default, DefaultMaxAge is used.
+	ResponseMaxAge time.Duration
+	// ResponseMaxSkew is the maximum time by which the created parameter of a response signature may be in the future,
+	// to allow for clock skew between the server and the client. By default, DefaultMaxSkew is used.
+	ResponseMaxSkew time.Duration
+	// Now returns the current time used to check the freshness of response signatures. If nil, time.Now is used.
+	Now func() time.Time
+	// Negotiate reports whether a request rejected with 401 Unauthorized is retried once with a signature
+	// requested in the Accept-Signature field of the response (RFC 9421 Section 5.1).
+	// The first requested signature whose keyid and alg parameters match KeyID and the Signer is used.
+	// Requests with a body that cannot be replayed are not retried.
+	Negotiate bool
 
 	signer Signer
 }
@@ -34,8 +97,14 @@ type Transport struct {
 // NewTransport returns a new [Transport] given a [Signer].
 func NewTransport(signer Signer) *Transport {
 	return &Transport{
-		Base:   http.DefaultTransport,
-		signer: signer,
+		Base:            http.DefaultTransport,
+		Components:      DefaultComponents,
+		DigestAlgorithm: DigestSHA256,
+		Nonce:           RandomNonce,
+		Label:           DefaultLabel,
+		ResponseMaxAge:  DefaultMaxAge,
+		ResponseMaxSkew: DefaultMaxSkew,
+		signer:          signer,
 	}
 }
 
@@ -50,92 +119,272 @@ func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
 		}()
 	}
 
+	req := r
 	r = r.Clone(r.Context()) // per RoundTripper contract.
-	if err := t.sign(r); err != nil {
+	if err := t.sign(r, t.Label, componentIDs(t.Components, false), t.Headers, t.Nonce); err != nil {
 		return nil, fmt.Errorf("sign request: %w", err)
 	}
 	bodyClosed = true // r.Body is closed by the base RoundTripper.
-	return t.Base.RoundTrip(r)
+	resp, err := t.Base.RoundTrip(r)
+	if err == nil && t.Negotiate && resp.StatusCode == http.StatusUnauthorized {
+		resp, r, err = t.negotiate(req, r, resp)
+	}
+	if err != nil || t.ResponseResolver == nil {
+		return resp, err
+	}
+	if err := t.verifyResponse(r, resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("verify response: %w", err)
+	}
+	return resp, nil
+}
+
+// negotiate retries the request req, sent signed as r, with a signature requested in the Accept-Signature field
+// of the unauthorized response resp. If no requested signature can be created, resp is returned as is.
+func (t *Transport) negotiate(req, r *http.Request, resp *http.Response) (*http.Response, *http.Request, error) {
+	accept, ok := t.acceptedSignature(resp.Header)
+	if !ok || (r.Body != nil && r.Body != http.NoBody && r.GetBody == nil) {
+		return resp, r, nil
+	}
+	retry := req.Clone(req.Context())
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return resp, r, nil
+		}
+		retry.Body = body
+	}
+
+	nonce := t.Nonce
+	if accept.nonce && nonce == nil {
+		nonce = RandomNonce
+	}
+	if err := t.sign(retry, accept.label, accept.components, nil, nonce); err != nil {
+		if retry.Body != nil {
+			_ = retry.Body.Close()
+		}
+		// The requested components may be missing from the request, such as an absent header field.
+		var cerr *componentError
+		if errors.As(err, &cerr) {
+			return resp, r, nil
+		}
+		_ = resp.Body.Close()
+		return nil, nil, fmt.Errorf("sign request: %w", err)
+	}
+	_ = resp.Body.Close()
+	resp, err := t.Base.RoundTrip(retry)
+	return resp, retry, err
 }
 
-func (t *Transport) sign(r *http.Request) error {
-	var (
-		method    = r.Method
-		host      = r.Host
-		path      = r.URL.EscapedPath()
-		query     = query{r.URL.Query()}.Encode()
-		timestamp = time.Now().UTC().Format(time.RFC3339)
-	)
-	if path == "" {
-		path = "/" // See https://www.rfc-editor.org/rfc/rfc9110#section-4.2.3
-	}
-	data := fmt.Sprintf("%s%s%s%s%s", method, host, path, query, timestamp)
-	sig, err := t.signer.Sign([]byte(data))
+// acceptedSignature returns the first signature requested in the Accept-Signature field of h
+// that the Transport can create.
+func (t *Transport) acceptedSignature(h http.Header) (acceptedSignature, bool) {
+	accepts, err := parseAcceptSignature(h)
 	if err != nil {
+		return acceptedSignature{}, false
+	}
+	alg := algorithm(t.signer)
+	for _, a := range accepts {
+		if (a.keyID == "" || a.keyID == t.KeyID) && (a.alg == "" || a.alg == alg) {
+			return a, true
+		}
+	}
+	return acceptedSignature{}, false
+}
+
+func (t *Transport) verifyResponse(r *http.Request, resp *http.Response) error {
+	v := verifier{
+		resolver:        t.ResponseResolver,
+		maxAge:          t.ResponseMaxAge,
+		maxSkew:         t.ResponseMaxSkew,
+		now:             t.Now,
+		optionalCreated: t.ResponseMaxAge == 0,
+	}
+	if t.RequireBoundResponse {
+		sigs, err := parseDictionaryField(r.Header, signatureHeader)
+		if err != nil {
+			return err
+		}
+		for _, sig := range sigs {
+			v.required = append(v.required, componentID{name: "signature", key: sig.Key, req: true})
+		}
+	}
+	if _, err := v.verify(r.Context(), message{req: r, status: resp.StatusCode, header: resp.Header}); err != nil {
 		return err
 	}
-	esig := base64.RawURLEncoding.EncodeToString(sig)
-	r.Header.Add(timestampHeader, timestamp)
-	r.Header.Add(signatureHeader, esig)
+	if len(resp.Header.Values(contentDigestHeader)) > 0 && bodyAllowed(r, resp.StatusCode) {
+		digests, err := parseContentDigest(resp.Header)
+		if err != nil {
+			return digestError(err)
+		}
+		resp.Body = &digestReader{rc: resp.Body, digests: digests}
+	}
 	return nil
 }
 
+// sign signs the request, adding the signature with the label covering the components and the header fields.
+func (t *Transport) sign(r *http.Request, label string, components []componentID, headers []string, nonceFunc func() (string, error)) error {
+	digested := t.DigestAlgorithm != "" && r.Body != nil && r.Body != http.NoBody
+	if digested {
+		body, err := readBody(r.Body)
+		if err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		r.ContentLength = int64(len(body))
+
+		digest, err := contentDigest(t.DigestAlgorithm, body)
+		if err != nil {
+			return err
+		}
+		r.Header.Set(contentDigestHeader, digest)
+	}
+	// The header fields are covered once Content-Digest is set, so that it can be one of them.
+	hs, err := headerComponents(r.Header, headers, t.MissingHeaders)
+	if err != nil {
+		return err
+	}
+	components = append(slices.Clip(components), hs...)
+	if id := (componentID{name: "content-digest"}); digested && !slices.Contains(components, id) {
+		components = append(components, id)
+	}
+
+	nonce, err := newNonce(nonceFunc)
+	if err != nil {
+		return err
+	}
+	sp := signatureParams{
+		components: components,
+		created:    time.Now(),
+		nonce:      nonce,
+		keyID:      t.KeyID,
+		alg:        algorithm(t.signer),
+	}
+	if t.TTL > 0 {
+		sp.expires = sp.created.Add(t.TTL)
+	}
+	return signMessage(r.Context(), t.signer, requestMessage(r), label, sp)
+}
+
 // DefaultErrorHandler handles errors as follows:
-//   - If the error is [ErrVerification], it sends a 401 Unauthorized response.
+//   - If the error is [ErrVerification] or [ErrMalformedSignature], it sends a 401 Unauthorized response.
+//     These errors mean that the request is not properly signed.
+//   - If the error is an [http.MaxBytesError], it sends a 413 Request Entity Too Large response.
 //   - For any other errors, it defaults to sending a 500 Internal Server Error response.
+//     These errors mean that verification could not be performed, for example because a [KeyResolver] failed.
 func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
 	if err == nil {
 		return
 	}
+	var mberr *http.MaxBytesError
 	switch {
-	case errors.Is(err, ErrVerification):
+	case errors.Is(err, ErrVerification), errors.Is(err, ErrMalformedSignature):
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	case errors.As(err, &mberr):
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
 	default:
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 	}
 }
 
+// Middleware is an HTTP middleware which verifies signatures of incoming HTTP requests.
 type Middleware struct {
 	// ErrorHandler is used to handle errors that occur during signature verification.
 	// If not provided, DefaultErrorHandler is used.
 	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	// AcceptSignatures are the signatures requested in the Accept-Signature field (RFC 9421 Section 5.1)
+	// of responses to requests that fail verification, so that clients can sign requests as expected.
+	// The created parameter is always requested, and the nonce parameter is requested if NonceStore is set.
+	// If empty, the field is not sent.
+	AcceptSignatures []AcceptSignature
+	// MaxAge is the maximum age of a signature, measured from its created parameter.
+	// If zero, the age is not limited, except for signatures without an expires parameter
+	// if NonceStore is set, which are limited to DefaultMaxAge so that their nonces expire.
+	// By default, DefaultMaxAge is used.
+	// Signatures with an expires parameter in the past are rejected regardless of MaxAge.
+	MaxAge time.Duration
+	// MaxSkew is the maximum time by which the created parameter of a signature may be in the future,
+	// to allow for clock skew between the client and the server. By default, DefaultMaxSkew is used.
+	MaxSkew time.Duration
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+	// Required are the components every signature must cover, such as ComponentMethod or "content-digest".
+	// Signatures that do not cover them are rejected before the signature is verified.
+	Required []string
+	// RequiredByMethod are the components every signature of a request with the method must cover,
+	// in addition to Required. The map is keyed by the request method, for example http.MethodPost.
+	RequiredByMethod map[string][]string
+	// Labels are the labels of the signatures to verify. Each of them must be present in a request,
+	// and signatures with other labels are ignored. If empty, every signature of a request is verified.
+	Labels []string
+	// NonceStore records the nonces of verified signatures to reject replayed requests.
+	// Nonces are kept until the signature expires or exceeds MaxAge.
+	// If not nil, signatures without a nonce parameter are rejected. By default, nonces are not checked.
+	NonceStore NonceStore
+	// MaxBodySize is the maximum size in bytes of a request body read into memory to verify its Content-Digest field.
+	// Requests with larger bodies are rejected with an [http.MaxBytesError].
+	// If zero, the size is not limited. By default, DefaultMaxBodySize is used.
+	MaxBodySize int64
 
-	verifier Verifier
+	resolver KeyResolver
 }
 
 // NewMiddleware returns a new [Middleware] given a [Verifier].
+// The Verifier is used regardless of the keyid signature parameter.
 func NewMiddleware(verifier Verifier) *Middleware {
+	return NewResolverMiddleware(SingleKey(verifier))
+}
+
+// NewResolverMiddleware returns a new [Middleware] given a [KeyResolver]
+// used to look up the [Verifier] by the keyid signature parameter.
+// Signatures by keys unknown to the resolver fail verification.
+func NewResolverMiddleware(resolver KeyResolver) *Middleware {
 	return &Middleware{
 		ErrorHandler: DefaultErrorHandler,
-		verifier:     verifier,
+		MaxAge:       DefaultMaxAge,
+		MaxSkew:      DefaultMaxSkew,
+		MaxBodySize:  DefaultMaxBodySize,
+		resolver:     resolver,
 	}
 }
 
 // Handler returns a handler that serves requests with signature verification.
+// Every signature in the request must be valid.
+// The results of the verified signatures are stored in the request context and retrieved with [FromContext].
+//
+// If the request has a Content-Digest field (RFC 9530), the body is read into memory and the digest is verified
+// before h is called, up to MaxBodySize bytes. The body remains readable by h.
 func (m *Middleware) Handler(h http.Handler) http.Handler {
 	return m.handler(func(w http.ResponseWriter, r *http.Request) error {
-		var (
-			method    = r.Method
-			host      = r.Host
-			path      = r.URL.EscapedPath()
-			query     = query{r.URL.Query()}.Encode()
-			timestamp = r.Header.Get(timestampHeader)
-		)
-		msg := fmt.Sprintf("%s%s%s%s%s", method, host, path, query, timestamp)
-
-		sig, err := base64.RawURLEncoding.DecodeString(r.Header.Get(signatureHeader))
-		if err != nil {
-			return fmt.Errorf("%w: %w", ErrVerification, err)
+		v := verifier{
+			resolver: m.resolver,
+			maxAge:   m.MaxAge,
+			maxSkew:  m.MaxSkew,
+			now:      m.Now,
+			nonces:   m.NonceStore,
+			labels:   m.Labels,
+			required: append(componentIDs(m.Required, false), componentIDs(m.RequiredByMethod[r.Method], false)...),
 		}
-
-		valid, err := m.verifier.Verify([]byte(msg), sig)
+		results, err := v.verify(r.Context(), requestMessage(r))
 		if err != nil {
 			return err
 		}
-		if !valid {
-			return ErrVerification
+		if len(r.Header.Values(contentDigestHeader)) > 0 {
+			rc := r.Body
+			if m.MaxBodySize > 0 {
+				rc = http.MaxBytesReader(w, rc, m.MaxBodySize)
+			}
+			body, err := readBody(rc)
+			if err != nil {
+				return fmt.Errorf("read body: %w", err)
+			}
+			if err := verifyContentDigest(r.Header, body); err != nil {
+				return digestError(err)
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
 		}
-		h.ServeHTTP(w, r)
+		h.ServeHTTP(w, r.WithContext(newContext(r.Context(), results)))
 		return nil
 	})
 }
@@ -143,37 +392,29 @@ func (m *Middleware) Handler(h http.Handler) http.Handler {
 func (m *Middleware) handler(h func(w http.ResponseWriter, r *http.Request) error) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if err := h(w, r); err != nil {
+			if len(m.AcceptSignatures) > 0 && (errors.Is(err, ErrVerification) || errors.Is(err, ErrMalformedSignature)) {
+				accept, aerr := acceptSignatureField(m.AcceptSignatures, m.NonceStore != nil)
+				if aerr != nil {
+					// The verification error is kept, so that the request is still rejected as unauthorized.
+					err = errors.Join(err, fmt.Errorf("%s field: %w", acceptSignatureHeader, aerr))
+				} else {
+					w.Header().Set(acceptSignatureHeader, accept)
+				}
+			}
 			m.ErrorHandler(w, r, err)
 		}
 	})
 }
 
-// query embeds [url.Values] overriding [url.Values.Encode] to sort by both key and value.
-type query struct{ url.Values }
-
-// Encode encodes the query parameters into “URL encoded” form ("bar=baz&foo=quux") sorted by key and value.
-func (q query) Encode() string {
-	if len(q.Values) == 0 {
-		return ""
-	}
-	var buf strings.Builder
-	keys := make([]string, 0, len(q.Values))
-	for k := range q.Values {
-		keys = append(keys, k)
-	}
-	slices.Sort(keys)
-	for _, k := range keys {
-		values := q.Values[k]
-		slices.Sort(values)
-		keyEscaped := url.QueryEscape(k)
-		for _, v := range values {
-			if buf.Len() > 0 {
-				buf.WriteByte('&')
-			}
-			buf.WriteString(keyEscaped)
-			buf.WriteByte('=')
-			buf.WriteString(url.QueryEscape(v))
-		}
+// parseDictionaryField parses the combined field lines of a dictionary structured field.
+func parseDictionaryField(h http.Header, name string) (structfield.Dictionary, error) {
+	lines := h.Values(name)
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	d, err := structfield.ParseDictionary(strings.Join(lines, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s field: %w", name, err)
 	}
-	return buf.String()
+	return d, nil
 }
@@ -1,38 +1,18 @@
 package httpsign
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestQueryEncode(t *testing.T) {
-	tests := []struct {
-		values  url.Values
-		encoded string
-	}{
-		{nil, ""},
-		{url.Values{}, ""},
-		{url.Values{"k": {"v"}}, "k=v"},
-		{url.Values{"k1": {"v1"}, "k2": {"v2"}}, "k1=v1&k2=v2"},
-		{url.Values{"k1": {"v1_1", "v1_2"}, "k2": {"v2"}}, "k1=v1_1&k1=v1_2&k2=v2"},
-		{url.Values{"k1": {"v1_2", "v1_1"}, "k2": {"v2"}}, "k1=v1_1&k1=v1_2&k2=v2"},
-		{url.Values{"k1": {"v1"}, "k2": {"v2"}, "k3": {"v3"}}, "k1=v1&k2=v2&k3=v3"},
-		{url.Values{"k1": {"v1"}, "k2": {"v2_1", "v2_2"}, "k3": {"v3_1", "v3_2"}}, "k1=v1&k2=v2_1&k2=v2_2&k3=v3_1&k3=v3_2"},
-		{url.Values{"k1": {"v1"}, "k2": {"v2_2", "v2_1"}, "k3": {"v3_1", "v3_2"}}, "k1=v1&k2=v2_1&k2=v2_2&k3=v3_1&k3=v3_2"},
-		{url.Values{"k1": {"v1"}, "k2": {"v2_2", "v2_1"}, "k3": {"v3_2", "v3_1"}}, "k1=v1&k2=v2_1&k2=v2_2&k3=v3_1&k3=v3_2"},
-		{url.Values{"k": {"v4", "v3", "v2", "v1"}}, "k=v1&k=v2&k=v3&k=v4"},
-	}
-	for _, tt := range tests {
-		if got := (query{tt.values}).Encode(); got != tt.encoded {
-			t.Errorf(`Encode(%+v) = %q, want %q`, tt.values, got, tt.encoded)
-		}
-	}
-}
-
 func loggingErrorHandler(t *testing.T) func(w http.ResponseWriter, r *http.Request, err error) {
 	return func(w http.ResponseWriter, r *http.Request, err error) {
 		t.Helper()
@@ -93,3 +73,293 @@ func TestHTTP(t *testing.T) {
 		}
 	}
 }
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestHTTP_Unauthorized(t *testing.T) {
+	m := NewMiddleware(stubVerifier{})
+	m.ErrorHandler = loggingErrorHandler(t)
+	s := httptest.NewServer(m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer s.Close()
+
+	tests := map[string]func(r *http.Request){
+		"unsigned": nil,
+		"tampered path": func(r *http.Request) {
+			r.URL.Path = "/admin"
+		},
+		"missing signature": func(r *http.Request) {
+			r.Header.Del(signatureHeader)
+		},
+		"malformed input": func(r *http.Request) {
+			r.Header.Set(signatureInputHeader, "sig1=(")
+		},
+	}
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if tamper != nil {
+					tamper(r)
+				}
+				return http.DefaultTransport.RoundTrip(r)
+			})
+			c := http.Client{Transport: tr}
+			if tamper == nil {
+				c.Transport = http.DefaultTransport
+			}
+			resp, err := c.Get(s.URL + "/p?k=v")
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Get(); code: %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+// signedRequest returns the request as sent by the transport.
+func signedRequest(t *testing.T, tr *Transport, r *http.Request) *http.Request {
+	t.Helper()
+	var signed *http.Request
+	tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		signed = r
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	resp, err := tr.RoundTrip(r)
+	if err != nil {
+		t.Fatalf("RoundTrip() error: %v", err)
+	}
+	resp.Body.Close()
+	return signed
+}
+
+// serve serves the request using the middleware and returns the verification error.
+func serve(m *Middleware, r *http.Request) error {
+	var verr error
+	m.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		verr = err
+		DefaultErrorHandler(w, r, err)
+	}
+	m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(httptest.NewRecorder(), r)
+	return verr
+}
+
+func TestMiddleware_Freshness(t *testing.T) {
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		offset time.Duration
+		err    error
+	}{
+		{"fresh", 0, 0, nil},
+		{"within max age", 0, 4 * time.Minute, nil},
+		{"stale", 0, 6 * time.Minute, ErrStaleSignature},
+		{"within skew", 0, -30 * time.Second, nil},
+		{"future", 0, -2 * time.Minute, ErrFutureSignature},
+		{"within ttl", 2 * time.Minute, time.Minute, nil},
+		{"expired", 2 * time.Minute, 3 * time.Minute, ErrExpiredSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			tr.TTL = tt.ttl
+			r := signedRequest(t, tr, httptest.NewRequest("GET", "http://example.com/", nil))
+			m := NewMiddleware(stubVerifier{})
+			m.Now = func() time.Time { return time.Now().Add(tt.offset) }
+			if err := serve(m, r); !errors.Is(err, tt.err) {
+				t.Errorf("Handler() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	t.Run("unlimited age", func(t *testing.T) {
+		r := signedRequest(t, NewTransport(stubSigner{}), httptest.NewRequest("GET", "http://example.com/", nil))
+		m := NewMiddleware(stubVerifier{})
+		m.MaxAge = 0
+		m.Now = func() time.Time { return time.Now().Add(24 * time.Hour) }
+		if err := serve(m, r); err != nil {
+			t.Errorf("Handler() error: %v", err)
+		}
+	})
+
+	t.Run("expired with unlimited age", func(t *testing.T) {
+		tr := NewTransport(stubSigner{})
+		tr.TTL = time.Hour
+		r := signedRequest(t, tr, httptest.NewRequest("GET", "http://example.com/", nil))
+		m := NewMiddleware(stubVerifier{})
+		m.MaxAge = 0
+		m.Now = func() time.Time { return time.Now().Add(24 * time.Hour) }
+		if err := serve(m, r); !errors.Is(err, ErrExpiredSignature) {
+			t.Errorf("Handler() error = %v, want %v", err, ErrExpiredSignature)
+		}
+	})
+
+	for _, input := range []string{`sig1=("@method")`, `sig1=("@method");created="now"`} {
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		r.Header.Set(signatureInputHeader, input)
+		r.Header.Set(signatureHeader, `sig1=:AAAA:`)
+		if err := serve(NewMiddleware(stubVerifier{}), r); !errors.Is(err, ErrVerification) {
+			t.Errorf("Handler() with %s = %q error = %v, want %v", signatureInputHeader, input, err, ErrVerification)
+		}
+	}
+}
+
+type errVerifier struct{ err error }
+
+func (v errVerifier) Verify(message []byte, signature []byte) (bool, error) {
+	return false, v.err
+}
+
+func TestMiddleware_VerifierErrors(t *testing.T) {
+	tests := []struct {
+		verifier Verifier
+		code     int
+	}{
+		{stubKey("other"), http.StatusUnauthorized},
+		{errVerifier{fmt.Errorf("bad length: %w", ErrMalformedSignature)}, http.StatusUnauthorized},
+		{errVerifier{errors.New("key service unavailable")}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		r := signedRequest(t, NewTransport(stubSigner{}), httptest.NewRequest("GET", "http://example.com/", nil))
+		m := NewMiddleware(tt.verifier)
+		m.ErrorHandler = loggingErrorHandler(t)
+		w := httptest.NewRecorder()
+		m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("Handler() with %#v; code: %d, want %d", tt.verifier, w.Code, tt.code)
+		}
+	}
+}
+
+func TestMiddleware_Labels(t *testing.T) {
+	client := NewTransport(stubKey("client"))
+	client.KeyID = "client"
+	client.Label = "client"
+	gateway := NewTransport(stubKey("gateway"))
+	gateway.KeyID = "gateway"
+	gateway.Label = "gateway"
+	gateway.Base = client
+	var r *http.Request
+	client.Base = roundTripperFunc(func(signed *http.Request) (*http.Response, error) {
+		r = signed
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: signed}, nil
+	})
+	if _, err := gateway.RoundTrip(httptest.NewRequest("GET", "http://example.com/", nil)); err != nil {
+		t.Fatalf("RoundTrip() error: %v", err)
+	}
+	keys := Keys{"client": stubKey("client"), "gateway": stubKey("gateway")}
+
+	tests := []struct {
+		labels []string
+		keys   Keys
+		reason Reason
+	}{
+		{nil, keys, 0},
+		{[]string{"client", "gateway"}, keys, 0},
+		{[]string{"gateway"}, Keys{"gateway": stubKey("gateway")}, 0},
+		{nil, Keys{"gateway": stubKey("gateway")}, ReasonUnknownKey},
+		{[]string{"client", "other"}, keys, ReasonMissingHeader},
+	}
+	for _, tt := range tests {
+		m := NewResolverMiddleware(tt.keys)
+		m.Labels = tt.labels
+		err := serve(m, r.Clone(r.Context()))
+		var verr *VerificationError
+		if tt.reason == 0 && err != nil || tt.reason != 0 && (!errors.As(err, &verr) || verr.Reason != tt.reason) {
+			t.Errorf("Handler() with labels %q error = %v, want reason %v", tt.labels, err, tt.reason)
+		}
+	}
+
+	if _, err := client.RoundTrip(r.Clone(r.Context())); err == nil {
+		t.Errorf("RoundTrip() with a used label error is nil")
+	}
+}
+
+func TestMiddleware_Required(t *testing.T) {
+	tests := []struct {
+		method     string
+		body       string
+		components []string
+		reason     Reason
+		component  string
+	}{
+		{"GET", "", []string{ComponentMethod, ComponentPath}, 0, ""},
+		{"GET", "", []string{ComponentMethod}, ReasonMissingComponent, `"@path"`},
+		{"POST", "hello", []string{ComponentMethod, ComponentPath}, 0, ""},
+		{"POST", "", []string{ComponentMethod, ComponentPath}, ReasonMissingComponent, `"content-digest"`},
+		{"DELETE", "", []string{ComponentPath}, ReasonMissingComponent, `"@method"`},
+		{"PUT", "hello", []string{ComponentMethod, "content-type"}, 0, ""},
+		{"PUT", "hello", []string{ComponentMethod}, ReasonMissingComponent, `"content-type"`},
+	}
+	for _, tt := range tests {
+		tr := NewTransport(stubSigner{})
+		tr.Components = tt.components
+		var body io.Reader
+		if tt.body != "" {
+			body = strings.NewReader(tt.body)
+		}
+		r := httptest.NewRequest(tt.method, "http://example.com/", body)
+		r.Header.Set("Content-Type", "text/plain")
+		r = signedRequest(t, tr, r)
+
+		m := NewMiddleware(errVerifier{errors.New("verifier called")})
+		if tt.reason == 0 {
+			m = NewMiddleware(stubVerifier{})
+		}
+		m.Required = []string{ComponentMethod}
+		m.RequiredByMethod = map[string][]string{
+			http.MethodGet:  {ComponentPath},
+			http.MethodPost: {"Content-Digest"},
+			http.MethodPut:  {"Content-Type"},
+		}
+		err := serve(m, r)
+		var verr *VerificationError
+		if tt.reason == 0 && err != nil || tt.reason != 0 && (!errors.As(err, &verr) || verr.Reason != tt.reason || verr.Component != tt.component) {
+			t.Errorf("Handler() %s covering %q error = %v, want reason %v for %s", tt.method, tt.components, err, tt.reason, tt.component)
+		}
+	}
+}
+
+type ctxKey struct{}
+
+// ctxSigner is a ContextSigner which signs messages with the value of ctxKey in the context.
+type ctxSigner struct{ stubSigner }
+
+func (ctxSigner) SignContext(ctx context.Context, message []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	v, _ := ctx.Value(ctxKey{}).(string)
+	return append([]byte(v), message...), nil
+}
+
+// ctxVerifier is a ContextVerifier which verifies signatures of ctxSigner.
+type ctxVerifier struct{ stubVerifier }
+
+func (ctxVerifier) VerifyContext(ctx context.Context, message []byte, signature []byte) (bool, error) {
+	v, _ := ctx.Value(ctxKey{}).(string)
+	return v+string(message) == string(signature), nil
+}
+
+func TestHTTP_Context(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "ctx")
+	r := signedRequest(t, NewTransport(ctxSigner{}), httptest.NewRequest("GET", "http://example.com/", nil).WithContext(ctx))
+
+	if err := serve(NewMiddleware(ctxVerifier{}), r); err != nil {
+		t.Errorf("Handler() with ContextVerifier error: %v", err)
+	}
+	if err := serve(NewMiddleware(stubVerifier{}), r); !errors.Is(err, ErrVerification) {
+		t.Errorf("Handler() with Verifier error = %v, want %v", err, ErrVerification)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	tr := NewTransport(ctxSigner{})
+	if _, err := tr.RoundTrip(httptest.NewRequest("GET", "http://example.com/", nil).WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip() with canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,160 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256" // for the algorithms using SHA-256
+	_ "crypto/sha512" // for the algorithms using SHA-384 and SHA-512
+	"errors"
+	"fmt"
+
+	"github.com/denpeshkov/httpsign"
+	hsecdsa "github.com/denpeshkov/httpsign/ecdsa"
+	hsed25519 "github.com/denpeshkov/httpsign/ed25519"
+	hshmac "github.com/denpeshkov/httpsign/hmac"
+	hsrsa "github.com/denpeshkov/httpsign/rsa"
+)
+
+var (
+	// ErrUnsupportedAlgorithm is returned when the algorithm of a key is unknown or missing.
+	ErrUnsupportedAlgorithm = errors.New("jwk: unsupported algorithm")
+	// ErrAlgorithmMismatch is returned when a key cannot be used with its algorithm.
+	ErrAlgorithmMismatch = errors.New("jwk: key does not match the algorithm")
+)
+
+// algorithm is the signature algorithm of a key.
+type algorithm struct {
+	kty  string
+	crv  string // for EC keys
+	hash crypto.Hash
+	pss  *rsa.PSSOptions // for RSA-PSS; nil for RSASSA-PKCS1-v1_5
+}
+
+// algorithms maps JOSE algorithm names (RFC 7518 Section 3.1)
+// and HTTP Signature Algorithms registry names to algorithms.
+var algorithms = map[string]algorithm{
+	"RS256": {kty: "RSA", hash: crypto.SHA256},
+	"RS384": {kty: "RSA", hash: crypto.SHA384},
+	"RS512": {kty: "RSA", hash: crypto.SHA512},
+	"PS256": {kty: "RSA", hash: crypto.SHA256, pss: &rsa.PSSOptions{Hash: crypto.SHA256, SaltLength: rsa.PSSSaltLengthEqualsHash}},
+	"PS384": {kty: "RSA", hash: crypto.SHA384, pss: &rsa.PSSOptions{Hash: crypto.SHA384, SaltLength: rsa.PSSSaltLengthEqualsHash}},
+	"PS512": {kty: "RSA", hash: crypto.SHA512, pss: &rsa.PSSOptions{Hash: crypto.SHA512, SaltLength: rsa.PSSSaltLengthEqualsHash}},
+	"ES256": {kty: "EC", crv: "P-256", hash: crypto.SHA256},
+	"ES384": {kty: "EC", crv: "P-384", hash: crypto.SHA384},
+	"ES512": {kty: "EC", crv: "P-521", hash: crypto.SHA512},
+	"EdDSA": {kty: "OKP"},
+	"HS256": {kty: "oct", hash: crypto.SHA256},
+	"HS384": {kty: "oct", hash: crypto.SHA384},
+	"HS512": {kty: "oct", hash: crypto.SHA512},
+
+	httpsign.AlgorithmRSAPSSSHA512:    {kty: "RSA", hash: crypto.SHA512, pss: &rsa.PSSOptions{Hash: crypto.SHA512, SaltLength: 64}},
+	httpsign.AlgorithmRSAPKCSSHA256:   {kty: "RSA", hash: crypto.SHA256},
+	httpsign.AlgorithmECDSAP256SHA256: {kty: "EC", crv: "P-256", hash: crypto.SHA256},
+	httpsign.AlgorithmECDSAP384SHA384: {kty: "EC", crv: "P-384", hash: crypto.SHA384},
+	httpsign.AlgorithmEd25519:         {kty: "OKP"},
+	httpsign.AlgorithmHMACSHA256:      {kty: "oct", hash: crypto.SHA256},
+}
+
+// joseNames maps HTTP Signature Algorithms registry names of asymmetric algorithms to JOSE algorithm names.
+var joseNames = map[string]string{
+	httpsign.AlgorithmRSAPSSSHA512:    "PS512",
+	httpsign.AlgorithmRSAPKCSSHA256:   "RS256",
+	httpsign.AlgorithmECDSAP256SHA256: "ES256",
+	httpsign.AlgorithmECDSAP384SHA384: "ES384",
+	httpsign.AlgorithmEd25519:         "EdDSA",
+}
+
+// defaultAlgorithms are the algorithms of EC and OKP keys without an alg member, by curve.
+// RSA and oct keys must have an alg member.
+var defaultAlgorithms = map[string]string{
+	"P-256":   "ES256",
+	"P-384":   "ES384",
+	"P-521":   "ES512",
+	"Ed25519": "EdDSA",
+}
+
+// algorithm returns the algorithm of the key and checks that the key can be used with it.
+func (k *Key) algorithm() (algorithm, error) {
+	kty, crv := keyType(k.Key)
+	name := k.Algorithm
+	if name == "" {
+		name = defaultAlgorithms[crv]
+	}
+	alg, ok := algorithms[name]
+	if !ok {
+		return algorithm{}, fmt.Errorf("%w: %q for %s key", ErrUnsupportedAlgorithm, k.Algorithm, kty)
+	}
+	if alg.kty != kty || (alg.crv != "" && alg.crv != crv) {
+		return algorithm{}, fmt.Errorf("%w: %s %s key for %q", ErrAlgorithmMismatch, crv, kty, name)
+	}
+	return alg, nil
+}
+
+// keyType returns the kty and crv members of the key.
+func keyType(key any) (kty, crv string) {
+	switch key := key.(type) {
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return "RSA", ""
+	case *ecdsa.PublicKey:
+		return "EC", curveNames[key.Curve]
+	case *ecdsa.PrivateKey:
+		return "EC", curveNames[key.Curve]
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return "OKP", "Ed25519"
+	case []byte:
+		return "oct", ""
+	default:
+		return fmt.Sprintf("%T", key), ""
+	}
+}
+
+// Signer returns the [httpsign.Signer] of the private or HMAC key for its algorithm.
+func (k *Key) Signer() (httpsign.Signer, error) {
+	alg, err := k.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	switch key := k.Key.(type) {
+	case *rsa.PrivateKey:
+		if alg.pss != nil {
+			return hsrsa.NewPSSSigner(key, alg.pss)
+		}
+		return hsrsa.NewPKCSSigner(key, alg.hash)
+	case *ecdsa.PrivateKey:
+		return hsecdsa.NewSigner(key, alg.hash)
+	case ed25519.PrivateKey:
+		return hsed25519.NewSigner(key)
+	case []byte:
+		return hshmac.New(key, alg.hash)
+	default:
+		return nil, fmt.Errorf("%w: %T is not a private key", ErrUnsupportedKey, key)
+	}
+}
+
+// Verifier returns the [httpsign.Verifier] of the key for its algorithm.
+func (k *Key) Verifier() (httpsign.Verifier, error) {
+	alg, err := k.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := k.Key.([]byte); ok {
+		return hshmac.New(key, alg.hash)
+	}
+	pub, err := k.Public()
+	if err != nil {
+		return nil, err
+	}
+	switch key := pub.Key.(type) {
+	case *rsa.PublicKey:
+		if alg.pss != nil {
+			return hsrsa.NewPSSVerifier(key, alg.pss)
+		}
+		return hsrsa.NewPKCSVerifier(key, alg.hash)
+	case *ecdsa.PublicKey:
+		return hsecdsa.NewVerifier(key, alg.hash)
+	default: // ed25519.PublicKey
+		return hsed25519.NewVerifier(key.(ed25519.PublicKey))
+	}
+}
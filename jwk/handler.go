@@ -0,0 +1,137 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/denpeshkov/httpsign"
+	hshmac "github.com/denpeshkov/httpsign/hmac"
+)
+
+// DefaultCacheMaxAge is the default max-age of the Cache-Control field of [Handler] responses.
+const DefaultCacheMaxAge = 10 * time.Minute
+
+// NewPublicKey returns the public key of the Verifier, with the key ID and the "sig" use.
+// The Verifier must have a Public() crypto.PublicKey method, as the Verifiers and Signers
+// of the rsa, ecdsa and ed25519 packages do.
+// The alg member is the JOSE name of the algorithm, so that the key can be used with it only.
+// Verifiers whose algorithm is not in the HTTP Signature Algorithms registry are not supported,
+// as the JOSE name cannot be determined.
+func NewPublicKey(keyID string, v httpsign.Verifier) (*Key, error) {
+	p, ok := v.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return nil, fmt.Errorf("%w: %T has no public key", ErrUnsupportedKey, v)
+	}
+	pub := p.Public()
+	switch key := pub.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+	case *ecdsa.PublicKey:
+		if _, ok := curveNames[key.Curve]; !ok {
+			return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, key.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+	}
+	var alg string
+	if a, ok := v.(interface{ Algorithm() string }); ok {
+		alg = joseNames[a.Algorithm()]
+	}
+	if alg == "" {
+		return nil, fmt.Errorf("%w: %T has no registered algorithm", ErrUnsupportedAlgorithm, v)
+	}
+	return &Key{KeyID: keyID, Algorithm: alg, Use: "sig", Key: pub}, nil
+}
+
+// Handler is an [http.Handler] serving the JSON Web Key Set of the public keys of Verifiers,
+// so that they can be resolved by a [RemoteSet].
+// Responses have an ETag and a Cache-Control field, and conditional requests are supported.
+// The zero value serves an empty set.
+// It is safe for concurrent use by multiple goroutines.
+type Handler struct {
+	// MaxAge is the max-age of the Cache-Control field of responses. By default, it is DefaultCacheMaxAge.
+	MaxAge time.Duration
+
+	set atomic.Pointer[encodedSet]
+}
+
+type encodedSet struct {
+	data []byte
+	etag string
+}
+
+// NewHandler returns a new [Handler] given Verifiers by key ID. See [Handler.SetKeys].
+func NewHandler(keys map[string]httpsign.Verifier) (*Handler, error) {
+	h := &Handler{MaxAge: DefaultCacheMaxAge}
+	if err := h.SetKeys(keys); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SetKeys replaces the served keys with the public keys of the Verifiers by key ID, as returned by [NewPublicKey].
+// Keys about to be used for signing should be served ahead of time, so that they are known when used.
+// HMAC keys are skipped, as they are secret.
+func (h *Handler) SetKeys(keys map[string]httpsign.Verifier) error {
+	keyIDs := make([]string, 0, len(keys))
+	for keyID := range keys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	set := Set{Keys: make([]Key, 0, len(keys))}
+	for _, keyID := range keyIDs {
+		switch keys[keyID].(type) {
+		case hshmac.HMAC, *hshmac.HMAC:
+			continue
+		}
+		k, err := NewPublicKey(keyID, keys[keyID])
+		if err != nil {
+			return fmt.Errorf("jwk: key %q: %w", keyID, err)
+		}
+		set.Keys = append(set.Keys, *k)
+	}
+	enc, err := encodeSet(set)
+	if err != nil {
+		return err
+	}
+	h.set.Store(enc)
+	return nil
+}
+
+// encodeSet returns the JSON encoding of the set with its ETag.
+func encodeSet(set Set) (*encodedSet, error) {
+	data, err := json.Marshal(set)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &encodedSet{data: data, etag: `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`}, nil
+}
+
+// ServeHTTP serves the JSON Web Key Set to GET and HEAD requests.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	set := h.set.Load()
+	if set == nil {
+		set, _ = encodeSet(Set{Keys: []Key{}}) // never fails
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(h.MaxAge/time.Second)))
+	w.Header().Set("ETag", set.etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(set.data))
+}
@@ -0,0 +1,164 @@
+package jwk
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/denpeshkov/httpsign"
+	hsecdsa "github.com/denpeshkov/httpsign/ecdsa"
+	hsed25519 "github.com/denpeshkov/httpsign/ed25519"
+	hshmac "github.com/denpeshkov/httpsign/hmac"
+	hsrsa "github.com/denpeshkov/httpsign/rsa"
+)
+
+func TestHandler(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	pss, err := hsrsa.NewPSSSigner(rsaKey, &rsa.PSSOptions{Hash: crypto.SHA512, SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		t.Fatalf("NewPSSSigner() error: %v", err)
+	}
+	ec, err := hsecdsa.NewSigner(p384Key, crypto.SHA384)
+	if err != nil {
+		t.Fatalf("NewSigner() error: %v", err)
+	}
+	ed, err := hsed25519.NewSigner(edKey)
+	if err != nil {
+		t.Fatalf("NewSigner() error: %v", err)
+	}
+	hmac, err := hshmac.New([]byte("secret"), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	signers := map[string]httpsign.Signer{"current": pss, "ec": ec, "next": ed}
+
+	h, err := NewHandler(map[string]httpsign.Verifier{"current": pss, "ec": &ec.Verifier, "next": ed, "hmac": hmac})
+	if err != nil {
+		t.Fatalf("NewHandler() error: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET code: %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":  "application/jwk-set+json",
+		"Cache-Control": "public, max-age=600",
+	} {
+		if got := resp.Header.Get(name); got != want {
+			t.Errorf("GET %s: %q, want %q", name, got, want)
+		}
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Errorf("GET has no ETag")
+	}
+	if s := string(body); strings.Contains(s, `"d"`) || strings.Contains(s, `"k"`) || strings.Contains(s, "hmac") {
+		t.Errorf("GET body %s has private or secret keys", s)
+	}
+	set, err := ParseSet(body)
+	if err != nil {
+		t.Fatalf("ParseSet(%s) error: %v", body, err)
+	}
+	wantAlgs := map[string]string{"current": "PS512", "ec": "ES384", "next": "EdDSA"}
+	if len(set.Keys) != len(wantAlgs) {
+		t.Errorf("GET body %s has %d keys, want %d", body, len(set.Keys), len(wantAlgs))
+	}
+	for keyID, alg := range wantAlgs {
+		if k, ok := set.Key(keyID); !ok || k.Algorithm != alg || k.Use != "sig" {
+			t.Errorf("Key(%q) = %+v, want alg %q and use %q", keyID, k, alg, "sig")
+		}
+	}
+
+	// The served keys resolve the verifiers of the signers.
+	remote := NewRemoteSet(srv.URL)
+	msg := []byte("test")
+	for keyID, sgn := range signers {
+		v, err := remote.Resolve(context.Background(), keyID)
+		if err != nil {
+			t.Fatalf("Resolve(%q) error: %v", keyID, err)
+		}
+		sig, err := sgn.Sign(msg)
+		if err != nil {
+			t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+		}
+		if ok, err := v.Verify(msg, sig); err != nil || !ok {
+			t.Errorf("Resolve(%q) Verifier does not verify the signature: %v", keyID, err)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("If-None-Match", etag)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNotModified {
+		t.Errorf("GET with If-None-Match: %v, %v, want code %d", resp, err, http.StatusNotModified)
+	} else {
+		resp.Body.Close()
+	}
+	if resp, err := http.Post(srv.URL, "text/plain", nil); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST: %v, %v, want code %d", resp, err, http.StatusMethodNotAllowed)
+	} else {
+		resp.Body.Close()
+	}
+
+	if err := h.SetKeys(map[string]httpsign.Verifier{"next": ed}); err != nil {
+		t.Fatalf("SetKeys() error: %v", err)
+	}
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Errorf("GET with If-None-Match after SetKeys: %v, %v, want code %d and new ETag", resp, err, http.StatusOK)
+	} else {
+		resp.Body.Close()
+	}
+
+	if _, err := NewHandler(map[string]httpsign.Verifier{"key": verifierFunc(nil)}); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("NewHandler() with Verifier without public key error = %v, want %v", err, ErrUnsupportedKey)
+	}
+	// Keys of unregistered algorithms cannot be described by the alg member.
+	pkcs512, err := hsrsa.NewPKCSVerifier(&rsaKey.PublicKey, crypto.SHA512)
+	if err != nil {
+		t.Fatalf("NewPKCSVerifier() error: %v", err)
+	}
+	if _, err := NewHandler(map[string]httpsign.Verifier{"key": pkcs512}); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("NewHandler() with RSA SHA-512 Verifier error = %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+
+	w := httptest.NewRecorder()
+	new(Handler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"keys":[]}` {
+		t.Errorf("zero Handler response: %d %s, want %d %s", w.Code, w.Body, http.StatusOK, `{"keys":[]}`)
+	}
+}
+
+type verifierFunc func(message, signature []byte) (bool, error)
+
+func (f verifierFunc) Verify(message, signature []byte) (bool, error) { return f(message, signature) }
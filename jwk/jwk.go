@@ -0,0 +1,321 @@
+// Package jwk provides utilities for parsing and serializing JSON Web Keys (RFC 7517)
+// and creating the matching [httpsign.Signer] and [httpsign.Verifier].
+//
+// RSA, EC (P-256, P-384 and P-521), OKP (Ed25519) and oct (HMAC) keys are supported.
+// The alg member may be either a JOSE algorithm name (RFC 7518), such as "PS512",
+// or an HTTP Signature Algorithms registry name, such as "rsa-pss-sha512".
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// ErrUnsupportedKey is returned when a key has an unsupported type or curve.
+	ErrUnsupportedKey = errors.New("jwk: unsupported key")
+	// ErrInvalidKey is returned when the members of a key are missing or invalid.
+	ErrInvalidKey = errors.New("jwk: invalid key")
+)
+
+// Key is a JSON Web Key.
+type Key struct {
+	// KeyID is the kid member, used as the keyid signature parameter.
+	KeyID string
+	// Algorithm is the alg member.
+	Algorithm string
+	// Use is the use member. Keys used for signatures have the "sig" use.
+	Use string
+	// Key is the key: *rsa.PublicKey, *rsa.PrivateKey, *ecdsa.PublicKey, *ecdsa.PrivateKey,
+	// ed25519.PublicKey, ed25519.PrivateKey or, for an HMAC key, []byte.
+	Key any
+}
+
+// jsonKey are the members of a JSON Web Key.
+type jsonKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	X  string `json:"x,omitempty"`
+	Y  string `json:"y,omitempty"`
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+	K  string `json:"k,omitempty"`
+}
+
+// ParseKey parses a JSON Web Key.
+func ParseKey(data []byte) (*Key, error) {
+	var k Key
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Public returns the public key of the key. It fails for an HMAC key.
+func (k *Key) Public() (*Key, error) {
+	pub := *k
+	switch key := k.Key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		pub.Key = key.(crypto.Signer).Public()
+	default:
+		return nil, fmt.Errorf("%w: %T has no public key", ErrUnsupportedKey, key)
+	}
+	return &pub, nil
+}
+
+// MarshalJSON implements the [json.Marshaler] interface.
+func (k Key) MarshalJSON() ([]byte, error) {
+	j := jsonKey{Kid: k.KeyID, Use: k.Use, Alg: k.Algorithm}
+	switch key := k.Key.(type) {
+	case *rsa.PublicKey:
+		j.setRSA(key)
+	case *rsa.PrivateKey:
+		if len(key.Primes) != 2 {
+			return nil, fmt.Errorf("%w: RSA key with %d primes", ErrUnsupportedKey, len(key.Primes))
+		}
+		j.setRSA(&key.PublicKey)
+		key.Precompute()
+		j.D = encodeInt(key.D, 0)
+		j.P = encodeInt(key.Primes[0], 0)
+		j.Q = encodeInt(key.Primes[1], 0)
+		j.DP = encodeInt(key.Precomputed.Dp, 0)
+		j.DQ = encodeInt(key.Precomputed.Dq, 0)
+		j.QI = encodeInt(key.Precomputed.Qinv, 0)
+	case *ecdsa.PublicKey:
+		if err := j.setEC(key); err != nil {
+			return nil, err
+		}
+	case *ecdsa.PrivateKey:
+		if err := j.setEC(&key.PublicKey); err != nil {
+			return nil, err
+		}
+		j.D = encodeInt(key.D, curveSize(key.Curve))
+	case ed25519.PublicKey:
+		j.Kty, j.Crv, j.X = "OKP", "Ed25519", encode(key)
+	case ed25519.PrivateKey:
+		j.Kty, j.Crv, j.X, j.D = "OKP", "Ed25519", encode(key.Public().(ed25519.PublicKey)), encode(key.Seed())
+	case []byte:
+		j.Kty, j.K = "oct", encode(key)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+	return json.Marshal(j)
+}
+
+func (j *jsonKey) setRSA(key *rsa.PublicKey) {
+	j.Kty = "RSA"
+	j.N = encodeInt(key.N, 0)
+	j.E = encodeInt(big.NewInt(int64(key.E)), 0)
+}
+
+func (j *jsonKey) setEC(key *ecdsa.PublicKey) error {
+	crv, ok := curveNames[key.Curve]
+	if !ok {
+		return fmt.Errorf("%w: curve %s", ErrUnsupportedKey, key.Curve.Params().Name)
+	}
+	size := curveSize(key.Curve)
+	j.Kty, j.Crv, j.X, j.Y = "EC", crv, encodeInt(key.X, size), encodeInt(key.Y, size)
+	return nil
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+func (k *Key) UnmarshalJSON(data []byte) error {
+	var j jsonKey
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	var key any
+	var err error
+	switch j.Kty {
+	case "RSA":
+		key, err = j.rsaKey()
+	case "EC":
+		key, err = j.ecKey()
+	case "OKP":
+		key, err = j.okpKey()
+	case "oct":
+		var b []byte
+		if b, err = decode(j.K); err == nil && len(b) == 0 {
+			err = errors.New("empty k")
+		}
+		key = b
+	default:
+		return fmt.Errorf("%w: kty %q", ErrUnsupportedKey, j.Kty)
+	}
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedKey) {
+			return err
+		}
+		return fmt.Errorf("%w: %s key: %w", ErrInvalidKey, j.Kty, err)
+	}
+	*k = Key{KeyID: j.Kid, Algorithm: j.Alg, Use: j.Use, Key: key}
+	return nil
+}
+
+func (j *jsonKey) rsaKey() (any, error) {
+	n, err := decodeInt(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("n: %w", err)
+	}
+	e, err := decodeInt(j.E)
+	if err != nil {
+		return nil, fmt.Errorf("e: %w", err)
+	}
+	if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("e out of range")
+	}
+	pub := &rsa.PublicKey{N: n, E: int(e.Int64())}
+	if j.D == "" {
+		return pub, nil
+	}
+	d, err := decodeInt(j.D)
+	if err != nil {
+		return nil, fmt.Errorf("d: %w", err)
+	}
+	p, err := decodeInt(j.P)
+	if err != nil {
+		return nil, fmt.Errorf("p: %w", err)
+	}
+	q, err := decodeInt(j.Q)
+	if err != nil {
+		return nil, fmt.Errorf("q: %w", err)
+	}
+	priv := &rsa.PrivateKey{PublicKey: *pub, D: d, Primes: []*big.Int{p, q}}
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+	priv.Precompute()
+	return priv, nil
+}
+
+func (j *jsonKey) ecKey() (any, error) {
+	curve, ok := curves[j.Crv]
+	if !ok {
+		return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKey, j.Crv)
+	}
+	size := curveSize(curve)
+	x, err := decodeFixed(j.X, size)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	y, err := decodeFixed(j.Y, size)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+	pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	if _, err := pub.ECDH(); err != nil {
+		return nil, err
+	}
+	if j.D == "" {
+		return pub, nil
+	}
+	d, err := decodeFixed(j.D, size)
+	if err != nil {
+		return nil, fmt.Errorf("d: %w", err)
+	}
+	priv := &ecdsa.PrivateKey{PublicKey: *pub, D: d}
+	ecdhPriv, err := priv.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	ecdhPub, _ := pub.ECDH()
+	if !ecdhPriv.PublicKey().Equal(ecdhPub) {
+		return nil, errors.New("d does not match the public key")
+	}
+	return priv, nil
+}
+
+func (j *jsonKey) okpKey() (any, error) {
+	if j.Crv != "Ed25519" {
+		return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKey, j.Crv)
+	}
+	x, err := decode(j.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("x: invalid Ed25519 public key")
+	}
+	if j.D == "" {
+		return ed25519.PublicKey(x), nil
+	}
+	d, err := decode(j.D)
+	if err != nil || len(d) != ed25519.SeedSize {
+		return nil, errors.New("d: invalid Ed25519 private key")
+	}
+	priv := ed25519.NewKeyFromSeed(d)
+	if !priv.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+		return nil, errors.New("d does not match the public key")
+	}
+	return priv, nil
+}
+
+var (
+	curves = map[string]elliptic.Curve{
+		"P-256": elliptic.P256(),
+		"P-384": elliptic.P384(),
+		"P-521": elliptic.P521(),
+	}
+	curveNames = map[elliptic.Curve]string{
+		elliptic.P256(): "P-256",
+		elliptic.P384(): "P-384",
+		elliptic.P521(): "P-521",
+	}
+)
+
+// curveSize returns the size in bytes of the coordinates of the curve.
+func curveSize(c elliptic.Curve) int {
+	return (c.Params().BitSize + 7) / 8
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// encodeInt encodes the big-endian integer, padded to size bytes.
+func encodeInt(v *big.Int, size int) string {
+	return encode(v.FillBytes(make([]byte, max(size, (v.BitLen()+7)/8))))
+}
+
+func decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("missing member")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := decode(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// decodeFixed decodes the big-endian integer of exactly size bytes.
+func decodeFixed(s string, size int) (*big.Int, error) {
+	b, err := decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("length %d, want %d", len(b), size)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,234 @@
+package jwk
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/denpeshkov/httpsign"
+)
+
+func TestKey_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+
+	tests := []Key{
+		{KeyID: "rsa-pss", Algorithm: "PS512", Key: rsaKey},
+		{KeyID: "rsa-pkcs", Algorithm: httpsign.AlgorithmRSAPKCSSHA256, Key: rsaKey},
+		{KeyID: "ec", Key: p521Key},
+		{KeyID: "ed", Use: "sig", Key: edKey},
+		{KeyID: "hmac", Algorithm: "HS256", Key: []byte("shared-secret")},
+	}
+	for _, k := range tests {
+		t.Run(k.KeyID, func(t *testing.T) {
+			data, err := json.Marshal(k)
+			if err != nil {
+				t.Fatalf("Marshal() error: %v", err)
+			}
+			got, err := ParseKey(data)
+			if err != nil {
+				t.Fatalf("ParseKey(%s) error: %v", data, err)
+			}
+			if got.KeyID != k.KeyID || got.Algorithm != k.Algorithm || got.Use != k.Use {
+				t.Errorf("ParseKey(%s) = %+v, want %+v", data, got, k)
+			}
+			if eq, ok := k.Key.(interface{ Equal(crypto.PrivateKey) bool }); ok && !eq.Equal(got.Key) {
+				t.Errorf("ParseKey(%s) key does not match", data)
+			}
+
+			sig, err := got.Signer()
+			if err != nil {
+				t.Fatalf("Signer() error: %v", err)
+			}
+			pub := got
+			if _, ok := got.Key.([]byte); !ok {
+				if pub, err = got.Public(); err != nil {
+					t.Fatalf("Public() error: %v", err)
+				}
+				data, err := json.Marshal(pub)
+				if err != nil {
+					t.Fatalf("Marshal() error: %v", err)
+				}
+				var members map[string]any
+				if err := json.Unmarshal(data, &members); err != nil {
+					t.Fatalf("Unmarshal() error: %v", err)
+				}
+				if _, ok := members["d"]; ok {
+					t.Errorf("public key %s has the d member", data)
+				}
+			}
+			ver, err := pub.Verifier()
+			if err != nil {
+				t.Fatalf("Verifier() error: %v", err)
+			}
+			msg := []byte("test")
+			sign, err := sig.Sign(msg)
+			if err != nil {
+				t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+			}
+			if ok, err := ver.Verify(msg, sign); err != nil || !ok {
+				t.Errorf("Signed message not verified by Verifier: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	// RFC 8037 Appendix A.1 and RFC 7517 Appendix A.1.
+	ed := `{"kty":"OKP","crv":"Ed25519","d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	k, err := ParseKey([]byte(ed))
+	if err != nil {
+		t.Fatalf("ParseKey(%s) error: %v", ed, err)
+	}
+	if _, ok := k.Key.(ed25519.PrivateKey); !ok {
+		t.Errorf("ParseKey(%s) key of type %T, want ed25519.PrivateKey", ed, k.Key)
+	}
+	ec := `{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM","use":"enc","kid":"1"}`
+	k, err = ParseKey([]byte(ec))
+	if err != nil {
+		t.Fatalf("ParseKey(%s) error: %v", ec, err)
+	}
+	if k.KeyID != "1" || k.Use != "enc" {
+		t.Errorf("ParseKey(%s) = %+v", ec, k)
+	}
+	if v, err := k.Verifier(); err != nil || v.(interface{ Algorithm() string }).Algorithm() != httpsign.AlgorithmECDSAP256SHA256 {
+		t.Errorf("Verifier() = %v, %v, want %s verifier", v, err, httpsign.AlgorithmECDSAP256SHA256)
+	}
+
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{`{"kty":"foo"}`, ErrUnsupportedKey},
+		{`{"kty":"EC","crv":"P-192","x":"AA","y":"AA"}`, ErrUnsupportedKey},
+		{`{"kty":"OKP","crv":"X25519","x":"AA"}`, ErrUnsupportedKey},
+		{`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"AAAA"}`, ErrInvalidKey},
+		{`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyA"}`, ErrInvalidKey},
+		{`{"kty":"OKP","crv":"Ed25519","d":"AAxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`, ErrInvalidKey},
+		{`{"kty":"RSA","n":"AQAB"}`, ErrInvalidKey},
+		{`{"kty":"oct"}`, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		if _, err := ParseKey([]byte(tt.in)); !errors.Is(err, tt.err) {
+			t.Errorf("ParseKey(%s) error = %v, want %v", tt.in, err, tt.err)
+		}
+	}
+}
+
+func TestKey_Algorithm(t *testing.T) {
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	tests := []struct {
+		key Key
+		err error
+	}{
+		{Key{Algorithm: "ES384", Key: p256Key}, ErrAlgorithmMismatch},
+		{Key{Algorithm: "RS256", Key: p256Key}, ErrAlgorithmMismatch},
+		{Key{Algorithm: "none", Key: p256Key}, ErrUnsupportedAlgorithm},
+		{Key{Key: []byte("secret")}, ErrUnsupportedAlgorithm},
+	}
+	for _, tt := range tests {
+		if _, err := tt.key.Verifier(); !errors.Is(err, tt.err) {
+			t.Errorf("Verifier() of %q %T key error = %v, want %v", tt.key.Algorithm, tt.key.Key, err, tt.err)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	priv := Key{KeyID: "ed", Key: edKey}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("Public() error: %v", err)
+	}
+	set := Set{Keys: []Key{*pub, {KeyID: "enc", Use: "enc", Key: p256Key.Public()}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	data = append(data[:len(data)-2], `,{"kty":"unknown","kid":"x"}]}`...)
+	parsed, err := ParseSet(data)
+	if err != nil {
+		t.Fatalf("ParseSet(%s) error: %v", data, err)
+	}
+	if len(parsed.Keys) != 2 {
+		t.Fatalf("ParseSet(%s) = %d keys, want 2", data, len(parsed.Keys))
+	}
+	if k, ok := parsed.Key("ed"); !ok || !reflect.DeepEqual(k.Key, pub.Key) {
+		t.Errorf("Key(%q) = %v, %t", "ed", k, ok)
+	}
+
+	sgn, err := priv.Signer()
+	if err != nil {
+		t.Fatalf("Signer() error: %v", err)
+	}
+	m := httpsign.NewResolverMiddleware(parsed)
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range []struct {
+		keyID string
+		code  int
+	}{
+		{"ed", http.StatusOK},
+		{"enc", http.StatusUnauthorized},
+		{"x", http.StatusUnauthorized},
+	} {
+		tr := httpsign.NewTransport(sgn)
+		tr.KeyID = tt.keyID
+		tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			return w.Result(), nil
+		})
+		resp, err := tr.RoundTrip(httptest.NewRequest("GET", "http://example.com/", nil))
+		if err != nil {
+			t.Fatalf("RoundTrip() error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("RoundTrip() with keyid %q; code: %d, want %d", tt.keyID, resp.StatusCode, tt.code)
+		}
+	}
+
+	if _, err := ParseSet([]byte(`{}`)); err == nil {
+		t.Errorf("ParseSet(%s) error is nil", `{}`)
+	}
+	if _, err := ParseSet([]byte(`{"keys":[{"kty":"oct"}]}`)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("ParseSet() with invalid key error = %v, want %v", err, ErrInvalidKey)
+	}
+	if _, err := parsed.Resolve(context.Background(), "x"); !errors.Is(err, httpsign.ErrKeyNotFound) {
+		t.Errorf("Resolve(%q) error = %v, want %v", "x", err, httpsign.ErrKeyNotFound)
+	}
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
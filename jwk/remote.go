@@ -0,0 +1,195 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/denpeshkov/httpsign"
+)
+
+const (
+	// DefaultRefreshInterval is the default interval between fetches of a [RemoteSet]
+	// when the response has no Cache-Control max-age directive.
+	DefaultRefreshInterval = time.Hour
+	// DefaultMinRefreshInterval is the default minimum interval between fetches of a [RemoteSet].
+	DefaultMinRefreshInterval = time.Minute
+)
+
+// maxSetSize is the maximum size of a fetched JSON Web Key Set.
+const maxSetSize = 1 << 20
+
+// RemoteSet is a [httpsign.KeyResolver] resolving the Verifier of a key by its key ID
+// from a JSON Web Key Set fetched from a URL.
+//
+// The set is fetched on the first resolution and cached for the max-age of the Cache-Control field of the response,
+// or RefreshInterval if absent. Once the cached set expires, it is still used while a new one is fetched in the background.
+// A key ID missing from the cached set causes the set to be fetched again, at most once per MinRefreshInterval.
+// If a fetch fails, the last fetched set is used.
+//
+// It is safe for concurrent use by multiple goroutines.
+type RemoteSet struct {
+	// Client is the HTTP client used to fetch the set. By default, it is http.DefaultClient.
+	Client *http.Client
+	// RefreshInterval is the interval between fetches when the response has no Cache-Control max-age directive.
+	// By default, it is DefaultRefreshInterval.
+	RefreshInterval time.Duration
+	// MinRefreshInterval is the minimum interval between fetches. It bounds the max-age of the response
+	// and rate-limits fetches caused by unknown key IDs or failures. By default, it is DefaultMinRefreshInterval.
+	MinRefreshInterval time.Duration
+
+	url string
+	now func() time.Time
+
+	mu      sync.Mutex
+	set     *Set
+	err     error         // error of the last fetch
+	expiry  time.Time     // time after which the set is refreshed
+	fetched time.Time     // time of the last fetch
+	done    chan struct{} // closed when the fetch in progress completes; nil if none
+}
+
+// NewRemoteSet returns a new [RemoteSet] given the URL of a JSON Web Key Set.
+func NewRemoteSet(url string) *RemoteSet {
+	return &RemoteSet{
+		Client:             http.DefaultClient,
+		RefreshInterval:    DefaultRefreshInterval,
+		MinRefreshInterval: DefaultMinRefreshInterval,
+		url:                url,
+		now:                time.Now,
+	}
+}
+
+// Resolve returns the Verifier of the key with the key ID.
+// Keys with a use member other than "sig" are ignored.
+func (s *RemoteSet) Resolve(ctx context.Context, keyID string) (httpsign.Verifier, error) {
+	set, err := s.keys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	v, err := set.Resolve(ctx, keyID)
+	if !errors.Is(err, httpsign.ErrKeyNotFound) {
+		return v, err
+	}
+	// The key may have been added to the set since it was fetched.
+	if set, ferr := s.keys(ctx, true); ferr == nil {
+		v, err = set.Resolve(ctx, keyID)
+	}
+	return v, err
+}
+
+// keys returns the cached set, fetching it if there is none or if force is set.
+func (s *RemoteSet) keys(ctx context.Context, force bool) (*Set, error) {
+	s.mu.Lock()
+	now := s.now()
+	limited := !s.fetched.IsZero() && now.Sub(s.fetched) < s.MinRefreshInterval
+	switch {
+	case s.set == nil && limited && s.done == nil:
+		defer s.mu.Unlock()
+		return nil, s.err
+	case s.set != nil && (limited || (!force && now.Before(s.expiry))):
+		defer s.mu.Unlock()
+		return s.set, nil
+	case s.set != nil && !force:
+		s.refresh(ctx)
+		defer s.mu.Unlock()
+		return s.set, nil
+	}
+	done := s.refresh(ctx)
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.set == nil {
+		return nil, s.err
+	}
+	return s.set, nil
+}
+
+// refresh fetches the set in the background unless a fetch is already in progress.
+// It returns a channel closed when the fetch completes. s.mu must be held.
+func (s *RemoteSet) refresh(ctx context.Context) <-chan struct{} {
+	if s.done != nil {
+		return s.done
+	}
+	done := make(chan struct{})
+	s.done = done
+	s.fetched = s.now()
+
+	// The fetch is shared by every caller, so it is not canceled with the context of the first one.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		set, maxAge, err := s.fetch(ctx)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err == nil {
+			s.set = set
+			s.expiry = s.now().Add(max(maxAge, s.MinRefreshInterval))
+		}
+		s.err = err
+		s.done = nil
+		close(done)
+	}()
+	return done
+}
+
+// fetch fetches the set and returns it with its max-age.
+func (s *RemoteSet) fetch(ctx context.Context) (*Set, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwk: fetch %s: %w", s.url, err)
+	}
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwk: fetch %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("jwk: fetch %s: unexpected status %s", s.url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSetSize+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwk: fetch %s: %w", s.url, err)
+	}
+	if len(data) > maxSetSize {
+		return nil, 0, fmt.Errorf("jwk: fetch %s: set exceeds %d bytes", s.url, maxSetSize)
+	}
+	set, err := ParseSet(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwk: fetch %s: %w", s.url, err)
+	}
+	return set, maxAge(resp.Header, s.RefreshInterval), nil
+}
+
+// maxAge returns the max-age of the Cache-Control field, or def if absent.
+// The no-cache and no-store directives give a zero max-age.
+func maxAge(h http.Header, def time.Duration) time.Duration {
+	age := def
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+			switch strings.ToLower(name) {
+			case "no-cache", "no-store":
+				return 0
+			case "max-age":
+				if n, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil && n >= 0 {
+					age = time.Duration(min(n, int64(1<<63-1)/int64(time.Second))) * time.Second
+				}
+			}
+		}
+	}
+	return age
+}
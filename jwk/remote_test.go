@@ -0,0 +1,198 @@
+package jwk
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denpeshkov/httpsign"
+)
+
+func TestRemoteSet(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		keys         []Key
+		cacheControl = "max-age=600"
+		status       = http.StatusOK
+		fetches      atomic.Int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		mu.Lock()
+		defer mu.Unlock()
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		_ = json.NewEncoder(w).Encode(Set{Keys: keys})
+	}))
+	defer srv.Close()
+
+	newKey := func(kid string) (httpsign.Signer, Key) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error: %v", err)
+		}
+		k := Key{KeyID: kid, Key: priv}
+		sgn, err := k.Signer()
+		if err != nil {
+			t.Fatalf("Signer() error: %v", err)
+		}
+		pub, err := k.Public()
+		if err != nil {
+			t.Fatalf("Public() error: %v", err)
+		}
+		return sgn, *pub
+	}
+	sgn1, key1 := newKey("key-1")
+	sgn2, key2 := newKey("key-2")
+	keys = []Key{key1}
+
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	advance := func(d time.Duration) { now.Add(int64(d)) }
+
+	s := NewRemoteSet(srv.URL)
+	s.now = func() time.Time { return time.Unix(0, now.Load()) }
+	ctx := context.Background()
+
+	check := func(keyID string, sgn httpsign.Signer, wantFetches int32) {
+		t.Helper()
+		v, err := s.Resolve(ctx, keyID)
+		if err != nil {
+			t.Fatalf("Resolve(%q) error: %v", keyID, err)
+		}
+		msg := []byte("test")
+		sig, err := sgn.Sign(msg)
+		if err != nil {
+			t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+		}
+		if ok, err := v.Verify(msg, sig); err != nil || !ok {
+			t.Errorf("Resolve(%q) Verifier does not verify the signature: %v", keyID, err)
+		}
+		wait(s)
+		if n := fetches.Load(); n != wantFetches {
+			t.Errorf("Resolve(%q); fetches: %d, want %d", keyID, n, wantFetches)
+		}
+	}
+
+	check("key-1", sgn1, 1)
+
+	// Unknown key IDs cause a fetch, at most once per MinRefreshInterval.
+	mu.Lock()
+	keys = []Key{key1, key2}
+	mu.Unlock()
+	if _, err := s.Resolve(ctx, "key-2"); !errors.Is(err, httpsign.ErrKeyNotFound) {
+		t.Errorf("Resolve(%q) error = %v, want %v", "key-2", err, httpsign.ErrKeyNotFound)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("Resolve(%q); fetches: %d, want %d", "key-2", n, 1)
+	}
+	advance(2 * time.Minute)
+	check("key-2", sgn2, 2)
+	advance(5 * time.Minute)
+	check("key-1", sgn1, 2) // cached
+	mu.Lock()
+	keys = []Key{key2}
+	mu.Unlock()
+
+	// The expired set is used while it is refreshed in the background.
+	advance(11 * time.Minute)
+	check("key-1", sgn1, 3)
+	if _, err := s.Resolve(ctx, "key-1"); !errors.Is(err, httpsign.ErrKeyNotFound) {
+		t.Errorf("Resolve(%q) after refresh error = %v, want %v", "key-1", err, httpsign.ErrKeyNotFound)
+	}
+
+	// The last fetched set is used if the endpoint fails.
+	mu.Lock()
+	status = http.StatusInternalServerError
+	mu.Unlock()
+	advance(11 * time.Minute)
+	check("key-2", sgn2, 4)
+	check("key-2", sgn2, 4)
+	advance(2 * time.Minute)
+	check("key-2", sgn2, 5)
+
+	// The max-age is bounded by MinRefreshInterval.
+	mu.Lock()
+	status, cacheControl = http.StatusOK, "no-store"
+	mu.Unlock()
+	advance(2 * time.Minute)
+	check("key-2", sgn2, 6)
+	advance(30 * time.Second)
+	check("key-2", sgn2, 6)
+}
+
+func TestRemoteSet_Errors(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s := NewRemoteSet(srv.URL)
+	for range 2 {
+		_, err := s.Resolve(context.Background(), "key")
+		if err == nil || errors.Is(err, httpsign.ErrKeyNotFound) {
+			t.Errorf("Resolve() error = %v, want fetch error", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetches: %d, want %d", n, 1)
+	}
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = NewRemoteSet(slow.URL)
+	if _, err := s.Resolve(ctx, "key"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Resolve() with canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want time.Duration
+	}{
+		{nil, time.Hour},
+		{[]string{"public, max-age=300"}, 5 * time.Minute},
+		{[]string{`max-age="60"`}, time.Minute},
+		{[]string{"public", "Max-Age=0"}, 0},
+		{[]string{"max-age=300, no-cache"}, 0},
+		{[]string{"no-store"}, 0},
+		{[]string{"max-age=-1"}, time.Hour},
+		{[]string{"max-age=99999999999999999999"}, time.Hour},
+	}
+	for _, tt := range tests {
+		h := http.Header{"Cache-Control": tt.in}
+		if got := maxAge(h, time.Hour); got != tt.want {
+			t.Errorf("maxAge(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// wait waits for the fetch in progress to complete.
+func wait(s *RemoteSet) {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
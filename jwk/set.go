@@ -0,0 +1,65 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/denpeshkov/httpsign"
+)
+
+// Set is a JSON Web Key Set.
+// It is a [httpsign.KeyResolver] resolving the Verifier of a key by its key ID.
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// ParseSet parses a JSON Web Key Set.
+// As recommended by RFC 7517 Section 5, keys of unsupported types or curves are ignored.
+func ParseSet(data []byte) (*Set, error) {
+	var raw struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Keys == nil {
+		return nil, errors.New("jwk: missing keys member")
+	}
+	s := &Set{Keys: make([]Key, 0, len(raw.Keys))}
+	for i, data := range raw.Keys {
+		var k Key
+		if err := json.Unmarshal(data, &k); err != nil {
+			if errors.Is(err, ErrUnsupportedKey) {
+				continue
+			}
+			return nil, fmt.Errorf("jwk: key %d: %w", i, err)
+		}
+		s.Keys = append(s.Keys, k)
+	}
+	return s, nil
+}
+
+// Key returns the key with the key ID.
+func (s *Set) Key(keyID string) (*Key, bool) {
+	for i := range s.Keys {
+		if s.Keys[i].KeyID == keyID {
+			return &s.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// Resolve returns the Verifier of the key with the key ID.
+// Keys with a use member other than "sig" are ignored.
+func (s *Set) Resolve(_ context.Context, keyID string) (httpsign.Verifier, error) {
+	for i := range s.Keys {
+		k := &s.Keys[i]
+		if k.KeyID != keyID || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		return k.Verifier()
+	}
+	return nil, fmt.Errorf("jwk: key %q: %w", keyID, httpsign.ErrKeyNotFound)
+}
@@ -0,0 +1,61 @@
+package httpsign
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrKeyNotFound is returned by a [KeyResolver] when the key is unknown.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrUntrustedKey is returned by a [KeyResolver] or a [Verifier] when the key is known but not trusted,
+	// for example because its certificate has expired.
+	ErrUntrustedKey = errors.New("untrusted key")
+)
+
+// KeyResolver resolves the [Verifier] of the key identified by the keyid signature parameter.
+// It must be safe for concurrent use by multiple goroutines.
+type KeyResolver interface {
+	// Resolve returns the Verifier of the key with the given ID.
+	// The key ID is empty if the signature has no keyid parameter.
+	// If the key is unknown, the returned error must wrap ErrKeyNotFound.
+	Resolve(ctx context.Context, keyID string) (Verifier, error)
+}
+
+// HeaderFromContext returns the header fields of the message whose signature is being verified.
+// It allows a [KeyResolver] to resolve keys sent in the message, such as certificates.
+func HeaderFromContext(ctx context.Context) (http.Header, bool) {
+	h, ok := ctx.Value(headerContextKey{}).(http.Header)
+	return h, ok
+}
+
+type headerContextKey struct{}
+
+// KeyResolverFunc is an adapter to allow the use of ordinary functions as a [KeyResolver].
+type KeyResolverFunc func(ctx context.Context, keyID string) (Verifier, error)
+
+// Resolve calls f(ctx, keyID).
+func (f KeyResolverFunc) Resolve(ctx context.Context, keyID string) (Verifier, error) {
+	return f(ctx, keyID)
+}
+
+// Keys is a [KeyResolver] mapping key IDs to Verifiers.
+type Keys map[string]Verifier
+
+// Resolve returns the Verifier of the key with the given ID.
+func (k Keys) Resolve(_ context.Context, keyID string) (Verifier, error) {
+	if v, ok := k[keyID]; ok {
+		return v, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// SingleKey returns a [KeyResolver] which resolves every key ID, including an empty one, to the Verifier.
+func SingleKey(v Verifier) KeyResolver {
+	return singleKey{v}
+}
+
+type singleKey struct{ v Verifier }
+
+func (k singleKey) Resolve(context.Context, string) (Verifier, error) { return k.v, nil }
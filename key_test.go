@@ -0,0 +1,83 @@
+package httpsign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubKey signs messages by prefixing them with the key.
+type stubKey string
+
+func (k stubKey) Sign(message []byte) ([]byte, error) {
+	return append([]byte(k), message...), nil
+}
+
+func (k stubKey) Verify(message []byte, signature []byte) (bool, error) {
+	return string(k)+string(message) == string(signature), nil
+}
+
+func TestResolverMiddleware(t *testing.T) {
+	errResolver := errors.New("resolver failure")
+	keys := Keys{"a": stubKey("a"), "b": stubKey("b")}
+	resolver := KeyResolverFunc(func(ctx context.Context, keyID string) (Verifier, error) {
+		switch keyID {
+		case "broken":
+			return nil, errResolver
+		case "untrusted":
+			return nil, fmt.Errorf("certificate expired: %w", ErrUntrustedKey)
+		case "header":
+			h, ok := HeaderFromContext(ctx)
+			if !ok || h.Get("X-Key") == "" {
+				return nil, ErrKeyNotFound
+			}
+			return stubKey(h.Get("X-Key")), nil
+		}
+		return keys.Resolve(ctx, keyID)
+	})
+
+	tests := []struct {
+		signer stubKey
+		keyID  string
+		header string
+		err    error
+		code   int
+	}{
+		{"a", "a", "", nil, http.StatusOK},
+		{"b", "b", "", nil, http.StatusOK},
+		{"a", "b", "", ErrVerification, http.StatusUnauthorized},
+		{"c", "c", "", ErrKeyNotFound, http.StatusUnauthorized},
+		{"a", "", "", ErrKeyNotFound, http.StatusUnauthorized},
+		{"a", "broken", "", errResolver, http.StatusInternalServerError},
+		{"a", "untrusted", "", ErrUntrustedKey, http.StatusUnauthorized},
+		{"c", "header", "c", nil, http.StatusOK},
+		{"c", "header", "", ErrKeyNotFound, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		tr := NewTransport(tt.signer)
+		tr.KeyID = tt.keyID
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		if tt.header != "" {
+			r.Header.Set("X-Key", tt.header)
+		}
+		r = signedRequest(t, tr, r)
+
+		m := NewResolverMiddleware(resolver)
+		var verr error
+		m.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			verr = err
+			DefaultErrorHandler(w, r, err)
+		}
+		w := httptest.NewRecorder()
+		m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+		if !errors.Is(verr, tt.err) {
+			t.Errorf("Handler() signed by %q with keyid %q error = %v, want %v", tt.signer, tt.keyID, verr, tt.err)
+		}
+		if w.Code != tt.code {
+			t.Errorf("Handler() signed by %q with keyid %q; code: %d, want %d", tt.signer, tt.keyID, w.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package keys provides utilities for loading keys and creating the matching
+// [httpsign.Signer] and [httpsign.Verifier] for an algorithm.
+//
+// Private keys are parsed from PKCS #1, PKCS #8 and SEC 1 encodings, and public keys from
+// PKIX, PKCS #1 and X.509 certificate encodings, either PEM or DER encoded.
+// Encrypted PEM blocks are not supported.
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // for the ECDSA and RSA algorithms
+	_ "crypto/sha512" // for the ECDSA and RSA algorithms
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/denpeshkov/httpsign"
+	hsecdsa "github.com/denpeshkov/httpsign/ecdsa"
+	hsed25519 "github.com/denpeshkov/httpsign/ed25519"
+	hsrsa "github.com/denpeshkov/httpsign/rsa"
+)
+
+var (
+	// ErrUnsupportedKey is returned when a key has an unsupported type or encoding.
+	ErrUnsupportedKey = errors.New("keys: unsupported key")
+	// ErrUnsupportedAlgorithm is returned when an algorithm is not supported.
+	ErrUnsupportedAlgorithm = errors.New("keys: unsupported algorithm")
+	// ErrAlgorithmMismatch is returned when a key cannot be used with an algorithm.
+	ErrAlgorithmMismatch = errors.New("keys: key does not match the algorithm")
+)
+
+// ParsePrivateKey parses a PEM or DER encoded private key.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	if block := decodePEM(data); block != nil {
+		var key any
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			return nil, fmt.Errorf("%w: PEM block type %q", ErrUnsupportedKey, block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("keys: parse %s: %w", block.Type, err)
+		}
+		return signer(key)
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(data); err == nil {
+		return signer(key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(data); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: not a PKCS #1, PKCS #8 or SEC 1 private key", ErrUnsupportedKey)
+}
+
+// decodePEM returns the first PEM block of data, or nil if there is none.
+// EC PARAMETERS blocks, such as those written by openssl ecparam -genkey before the key, are skipped.
+func decodePEM(data []byte) *pem.Block {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil || block.Type != "EC PARAMETERS" {
+			return block
+		}
+		data = rest
+	}
+}
+
+func signer(key any) (crypto.Signer, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key.(crypto.Signer), nil
+	default:
+		return nil, fmt.Errorf("%w: private key of type %T", ErrUnsupportedKey, key)
+	}
+}
+
+// ParsePublicKey parses a PEM or DER encoded public key or X.509 certificate,
+// returning the public key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	if block := decodePEM(data); block != nil {
+		var key any
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			return nil, fmt.Errorf("%w: PEM block type %q", ErrUnsupportedKey, block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("keys: parse %s: %w", block.Type, err)
+		}
+		return publicKey(key)
+	}
+	if key, err := x509.ParsePKIXPublicKey(data); err == nil {
+		return publicKey(key)
+	}
+	if key, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		return key, nil
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return publicKey(cert.PublicKey)
+	}
+	return nil, fmt.Errorf("%w: not a PKIX or PKCS #1 public key or X.509 certificate", ErrUnsupportedKey)
+}
+
+func publicKey(key any) (crypto.PublicKey, error) {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: public key of type %T", ErrUnsupportedKey, key)
+	}
+}
+
+// NewSigner returns the [httpsign.Signer] of the algorithm for the private key.
+// The algorithm is a name from the HTTP Signature Algorithms registry, such as [httpsign.AlgorithmEd25519].
+// HMAC is not supported, as it uses a shared secret rather than a key pair; use the hmac package instead.
+func NewSigner(priv crypto.Signer, alg string) (httpsign.Signer, error) {
+	if err := checkKey(priv.Public(), alg); err != nil {
+		return nil, err
+	}
+	switch alg {
+	case httpsign.AlgorithmRSAPSSSHA512:
+		return hsrsa.NewPSSCryptoSigner(priv, pssOptions)
+	case httpsign.AlgorithmRSAPKCSSHA256:
+		return hsrsa.NewPKCSCryptoSigner(priv, crypto.SHA256)
+	case httpsign.AlgorithmECDSAP256SHA256:
+		return hsecdsa.NewCryptoSigner(priv, crypto.SHA256)
+	case httpsign.AlgorithmECDSAP384SHA384:
+		return hsecdsa.NewCryptoSigner(priv, crypto.SHA384)
+	default: // httpsign.AlgorithmEd25519
+		return hsed25519.NewCryptoSigner(priv)
+	}
+}
+
+// NewVerifier returns the [httpsign.Verifier] of the algorithm for the public key.
+// If pub is a [crypto.Signer], its public key is used.
+// The algorithm is a name from the HTTP Signature Algorithms registry, such as [httpsign.AlgorithmEd25519].
+// HMAC is not supported, as it uses a shared secret rather than a key pair; use the hmac package instead.
+func NewVerifier(pub crypto.PublicKey, alg string) (httpsign.Verifier, error) {
+	if s, ok := pub.(crypto.Signer); ok {
+		pub = s.Public()
+	}
+	if err := checkKey(pub, alg); err != nil {
+		return nil, err
+	}
+	switch alg {
+	case httpsign.AlgorithmRSAPSSSHA512:
+		return hsrsa.NewPSSVerifier(pub.(*rsa.PublicKey), pssOptions)
+	case httpsign.AlgorithmRSAPKCSSHA256:
+		return hsrsa.NewPKCSVerifier(pub.(*rsa.PublicKey), crypto.SHA256)
+	case httpsign.AlgorithmECDSAP256SHA256:
+		return hsecdsa.NewVerifier(pub.(*ecdsa.PublicKey), crypto.SHA256)
+	case httpsign.AlgorithmECDSAP384SHA384:
+		return hsecdsa.NewVerifier(pub.(*ecdsa.PublicKey), crypto.SHA384)
+	default: // httpsign.AlgorithmEd25519
+		return hsed25519.NewVerifier(pub.(ed25519.PublicKey))
+	}
+}
+
+// LoadSigner reads a PEM or DER encoded private key from the named file
+// and returns the [httpsign.Signer] of the algorithm for it.
+func LoadSigner(name, alg string) (httpsign.Signer, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return NewSigner(priv, alg)
+}
+
+// LoadVerifier reads a PEM or DER encoded public key or X.509 certificate from the named file
+// and returns the [httpsign.Verifier] of the algorithm for it.
+func LoadVerifier(name, alg string) (httpsign.Verifier, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := ParsePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return NewVerifier(pub, alg)
+}
+
+// pssOptions are the RSA-PSS options of the rsa-pss-sha512 algorithm (RFC 9421 Section 3.3.1).
+var pssOptions = &rsa.PSSOptions{Hash: crypto.SHA512, SaltLength: 64}
+
+// checkKey checks that the public key can be used with the algorithm.
+func checkKey(pub crypto.PublicKey, alg string) error {
+	var ok bool
+	switch alg {
+	case httpsign.AlgorithmRSAPSSSHA512, httpsign.AlgorithmRSAPKCSSHA256:
+		_, ok = pub.(*rsa.PublicKey)
+	case httpsign.AlgorithmECDSAP256SHA256:
+		k, isECDSA := pub.(*ecdsa.PublicKey)
+		ok = isECDSA && k.Curve == elliptic.P256()
+	case httpsign.AlgorithmECDSAP384SHA384:
+		k, isECDSA := pub.(*ecdsa.PublicKey)
+		ok = isECDSA && k.Curve == elliptic.P384()
+	case httpsign.AlgorithmEd25519:
+		_, ok = pub.(ed25519.PublicKey)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %T for %q", ErrAlgorithmMismatch, pub, alg)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/denpeshkov/httpsign"
+)
+
+func TestParseKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		alg  string
+		priv []byte
+		pub  []byte
+	}{
+		{"PSS PKCS #1", httpsign.AlgorithmRSAPSSSHA512, pemBlock("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), pemBlock("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))},
+		{"PKCS PKCS #8", httpsign.AlgorithmRSAPKCSSHA256, pemBlock("PRIVATE KEY", pkcs8(t, rsaKey)), pemBlock("PUBLIC KEY", pkixKey(t, rsaKey.Public()))},
+		{"PSS DER", httpsign.AlgorithmRSAPSSSHA512, x509.MarshalPKCS1PrivateKey(rsaKey), x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)},
+		{"P-256 SEC 1", httpsign.AlgorithmECDSAP256SHA256, pemBlock("EC PRIVATE KEY", sec1(t, p256Key)), pemBlock("PUBLIC KEY", pkixKey(t, p256Key.Public()))},
+		{"P-256 EC PARAMETERS", httpsign.AlgorithmECDSAP256SHA256, append(pemBlock("EC PARAMETERS", p256OID), pemBlock("EC PRIVATE KEY", sec1(t, p256Key))...), append(pemBlock("EC PARAMETERS", p256OID), pemBlock("PUBLIC KEY", pkixKey(t, p256Key.Public()))...)},
+		{"P-384 DER", httpsign.AlgorithmECDSAP384SHA384, sec1(t, p384Key), pkixKey(t, p384Key.Public())},
+		{"Ed25519 PKCS #8", httpsign.AlgorithmEd25519, pemBlock("PRIVATE KEY", pkcs8(t, edKey)), pemBlock("CERTIFICATE", certificate(t, edKey))},
+		{"Ed25519 DER", httpsign.AlgorithmEd25519, pkcs8(t, edKey), certificate(t, edKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priv, err := ParsePrivateKey(tt.priv)
+			if err != nil {
+				t.Fatalf("ParsePrivateKey() error: %v", err)
+			}
+			pub, err := ParsePublicKey(tt.pub)
+			if err != nil {
+				t.Fatalf("ParsePublicKey() error: %v", err)
+			}
+			sig, err := NewSigner(priv, tt.alg)
+			if err != nil {
+				t.Fatalf("NewSigner() error: %v", err)
+			}
+			ver, err := NewVerifier(pub, tt.alg)
+			if err != nil {
+				t.Fatalf("NewVerifier() error: %v", err)
+			}
+			for _, v := range []any{sig, ver} {
+				if alg := v.(interface{ Algorithm() string }).Algorithm(); alg != tt.alg {
+					t.Errorf("%T.Algorithm() = %q, want %q", v, alg, tt.alg)
+				}
+			}
+
+			msg := []byte("test")
+			sign, err := sig.Sign(msg)
+			if err != nil {
+				t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+			}
+			if ok, err := ver.Verify(msg, sign); err != nil || !ok {
+				t.Errorf("Signed message not verified by Verifier: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewSigner_Errors(t *testing.T) {
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	tests := []struct {
+		alg string
+		err error
+	}{
+		{httpsign.AlgorithmECDSAP384SHA384, ErrAlgorithmMismatch},
+		{httpsign.AlgorithmRSAPSSSHA512, ErrAlgorithmMismatch},
+		{httpsign.AlgorithmEd25519, ErrAlgorithmMismatch},
+		{httpsign.AlgorithmHMACSHA256, ErrUnsupportedAlgorithm},
+		{"", ErrUnsupportedAlgorithm},
+	}
+	for _, tt := range tests {
+		if _, err := NewSigner(p256Key, tt.alg); !errors.Is(err, tt.err) {
+			t.Errorf("NewSigner(P-256 key, %q) error = %v, want %v", tt.alg, err, tt.err)
+		}
+		if _, err := NewVerifier(p256Key, tt.alg); !errors.Is(err, tt.err) {
+			t.Errorf("NewVerifier(P-256 key, %q) error = %v, want %v", tt.alg, err, tt.err)
+		}
+	}
+
+	for _, data := range [][]byte{
+		[]byte("not a key"),
+		pemBlock("ENCRYPTED PRIVATE KEY", []byte("data")),
+		pemBlock("PRIVATE KEY", []byte("data")),
+	} {
+		if _, err := ParsePrivateKey(data); err == nil {
+			t.Errorf("ParsePrivateKey(%q) error is nil", data)
+		}
+		if _, err := ParsePublicKey(data); err == nil {
+			t.Errorf("ParsePublicKey(%q) error is nil", data)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	dir := t.TempDir()
+	privFile := filepath.Join(dir, "key.pem")
+	pubFile := filepath.Join(dir, "key.pub")
+	if err := os.WriteFile(privFile, pemBlock("PRIVATE KEY", pkcs8(t, priv)), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if err := os.WriteFile(pubFile, pemBlock("PUBLIC KEY", pkixKey(t, pub)), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	sig, err := LoadSigner(privFile, httpsign.AlgorithmEd25519)
+	if err != nil {
+		t.Fatalf("LoadSigner() error: %v", err)
+	}
+	ver, err := LoadVerifier(pubFile, httpsign.AlgorithmEd25519)
+	if err != nil {
+		t.Fatalf("LoadVerifier() error: %v", err)
+	}
+	msg := []byte("test")
+	sign, err := sig.Sign(msg)
+	if err != nil {
+		t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+	}
+	if ok, err := ver.Verify(msg, sign); err != nil || !ok {
+		t.Errorf("Signed message not verified by Verifier: %v", err)
+	}
+
+	if _, err := LoadSigner(filepath.Join(dir, "missing.pem"), httpsign.AlgorithmEd25519); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadSigner(missing file) error = %v, want %v", err, os.ErrNotExist)
+	}
+	if _, err := LoadSigner(pubFile, httpsign.AlgorithmEd25519); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("LoadSigner(public key file) error = %v, want %v", err, ErrUnsupportedKey)
+	}
+}
+
+// p256OID is the DER encoded named curve of an EC PARAMETERS block of a P-256 key.
+var p256OID = []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+
+func pemBlock(typ string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+func pkcs8(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error: %v", err)
+	}
+	return der
+}
+
+func sec1(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error: %v", err)
+	}
+	return der
+}
+
+func pkixKey(t *testing.T, key crypto.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error: %v", err)
+	}
+	return der
+}
+
+func certificate(t *testing.T, key crypto.Signer) []byte {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error: %v", err)
+	}
+	return der
+}
@@ -0,0 +1,91 @@
+package httpsign
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// NonceStore records the nonces of verified signatures to detect replayed requests.
+// It must be safe for concurrent use by multiple goroutines.
+type NonceStore interface {
+	// Add records the nonce until the expiry time and reports whether the nonce was not already recorded.
+	// A zero expiry time means the nonce never expires.
+	Add(ctx context.Context, nonce string, expiry time.Time) (bool, error)
+}
+
+// RandomNonce returns a random 128-bit nonce encoded as unpadded base64url.
+func RandomNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+const (
+	nonceShards        = 32
+	nonceSweepInterval = time.Minute
+)
+
+// MemoryNonceStore is a [NonceStore] which keeps nonces in memory until they expire.
+// Nonces are sharded to reduce lock contention, and expired nonces are removed periodically as new nonces are added.
+// The zero value is ready to use, so that it can be created as a literal setting Now,
+// and must not be copied after first use.
+// It is safe for concurrent use by multiple goroutines.
+type MemoryNonceStore struct {
+	// Now returns the current time used to expire nonces, which should be the clock of the Middleware.
+	// If nil, time.Now is used.
+	Now func() time.Time
+
+	once   sync.Once // initializes seed
+	seed   maphash.Seed
+	shards [nonceShards]nonceShard
+}
+
+type nonceShard struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time // created on first use
+	sweep  time.Time            // time of the next sweep of expired nonces
+}
+
+// NewMemoryNonceStore returns a new [MemoryNonceStore].
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{}
+}
+
+// Add records the nonce until the expiry time and reports whether the nonce was not already recorded.
+func (s *MemoryNonceStore) Add(_ context.Context, nonce string, expiry time.Time) (bool, error) {
+	s.once.Do(func() { s.seed = maphash.MakeSeed() })
+	sh := &s.shards[maphash.String(s.seed, nonce)%nonceShards]
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.nonces == nil {
+		sh.nonces = make(map[string]time.Time)
+	}
+	if now.After(sh.sweep) {
+		for n, exp := range sh.nonces {
+			if expired(exp, now) {
+				delete(sh.nonces, n)
+			}
+		}
+		sh.sweep = now.Add(nonceSweepInterval)
+	}
+	if exp, ok := sh.nonces[nonce]; ok && !expired(exp, now) {
+		return false, nil
+	}
+	sh.nonces[nonce] = expiry
+	return true, nil
+}
+
+func expired(expiry, now time.Time) bool {
+	return !expiry.IsZero() && !now.Before(expiry)
+}
@@ -0,0 +1,154 @@
+package httpsign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := &MemoryNonceStore{Now: func() time.Time { return now }}
+
+	add := func(nonce string, expiry time.Time, want bool) {
+		t.Helper()
+		got, err := s.Add(ctx, nonce, expiry)
+		if err != nil {
+			t.Fatalf("Add(%q) error: %v", nonce, err)
+		}
+		if got != want {
+			t.Errorf("Add(%q) = %t, want %t", nonce, got, want)
+		}
+	}
+	add("n1", now.Add(time.Minute), true)
+	add("n1", now.Add(time.Minute), false)
+	add("n2", now.Add(time.Minute), true)
+	add("n3", time.Time{}, true)
+
+	now = now.Add(time.Minute)
+	add("n1", now.Add(time.Minute), true) // expired
+	add("n3", now.Add(time.Minute), false)
+
+	now = now.Add(2 * nonceSweepInterval)
+	add("n4", time.Time{}, true)
+	for i := range s.shards {
+		sh := &s.shards[i]
+		if sh.sweep.IsZero() {
+			continue
+		}
+		for n, exp := range sh.nonces {
+			if sh.sweep.After(now) && expired(exp, now) {
+				t.Errorf("nonce %q not removed by sweep", n)
+			}
+		}
+	}
+}
+
+func TestMemoryNonceStore_Concurrent(t *testing.T) {
+	s := NewMemoryNonceStore()
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Minute)
+
+	testf := func() {
+		for i := range 100 {
+			nonce := fmt.Sprint(i)
+			if _, err := s.Add(ctx, nonce, expiry); err != nil {
+				t.Errorf("Add(%q) error: %v", nonce, err)
+			}
+		}
+	}
+	// Test for concurrency safety using the -race flag.
+	t.Run("g1", func(t *testing.T) {
+		t.Parallel()
+		testf()
+	})
+	t.Run("g2", func(t *testing.T) {
+		t.Parallel()
+		testf()
+	})
+}
+
+func TestMiddleware_Replay(t *testing.T) {
+	m := NewMiddleware(stubVerifier{})
+	m.NonceStore = NewMemoryNonceStore()
+
+	r := signedRequest(t, NewTransport(stubSigner{}), httptest.NewRequest("GET", "http://example.com/", nil))
+	if err := serve(m, r); err != nil {
+		t.Fatalf("Handler() error: %v", err)
+	}
+	if err := serve(m, r); !errors.Is(err, ErrReplayedSignature) {
+		t.Errorf("Handler() for replayed request error = %v, want %v", err, ErrReplayedSignature)
+	}
+
+	r = signedRequest(t, NewTransport(stubSigner{}), httptest.NewRequest("GET", "http://example.com/", nil))
+	if err := serve(m, r); err != nil {
+		t.Errorf("Handler() for new request error: %v", err)
+	}
+
+	tr := NewTransport(stubSigner{})
+	tr.Nonce = nil
+	r = signedRequest(t, tr, httptest.NewRequest("GET", "http://example.com/", nil))
+	if err := serve(m, r); !errors.Is(err, ErrVerification) {
+		t.Errorf("Handler() for request without nonce error = %v, want %v", err, ErrVerification)
+	}
+}
+
+func TestMiddleware_ReplayWithoutMaxAge(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	s := NewMemoryNonceStore()
+	s.Now = clock
+	m := NewMiddleware(stubVerifier{})
+	m.MaxAge, m.Now, m.NonceStore = 0, clock, s
+
+	r := signedRequest(t, NewTransport(stubSigner{}), httptest.NewRequest("GET", "http://example.com/", nil))
+	if err := serve(m, r); err != nil {
+		t.Fatalf("Handler() error: %v", err)
+	}
+	for i := range s.shards {
+		for n, exp := range s.shards[i].nonces {
+			if exp.IsZero() || exp.After(now.Add(DefaultMaxAge)) {
+				t.Errorf("nonce %q expiry = %v, want at most %v", n, exp, now.Add(DefaultMaxAge))
+			}
+		}
+	}
+	if err := serve(m, r); !errors.Is(err, ErrReplayedSignature) {
+		t.Errorf("Handler() for replayed request error = %v, want %v", err, ErrReplayedSignature)
+	}
+
+	// Once the nonce expires, the signature is too old to be replayed.
+	now = now.Add(DefaultMaxAge + time.Second)
+	if err := serve(m, r); !errors.Is(err, ErrStaleSignature) {
+		t.Errorf("Handler() for request replayed after %v error = %v, want %v", DefaultMaxAge, err, ErrStaleSignature)
+	}
+}
+
+func TestMiddleware_ReplayRejectedMessage(t *testing.T) {
+	keys := Keys{"a": stubVerifier{}}
+	m := NewResolverMiddleware(keys)
+	m.NonceStore = NewMemoryNonceStore()
+
+	first := NewTransport(stubSigner{})
+	first.KeyID = "a"
+	second := NewTransport(stubSigner{})
+	second.Label, second.KeyID = "sig2", "b"
+	r := signedRequest(t, first, httptest.NewRequest("GET", "http://example.com/", nil))
+	r = signedRequest(t, second, r)
+
+	var verr *VerificationError
+	if err := serve(m, r); !errors.As(err, &verr) || verr.Reason != ReasonUnknownKey {
+		t.Fatalf("Handler() with unknown key error = %v, want reason %v", err, ReasonUnknownKey)
+	}
+	// The nonce of the verified signature is not used up by the rejected request.
+	keys["b"] = stubVerifier{}
+	if err := serve(m, r); err != nil {
+		t.Errorf("Handler() for retried request error: %v", err)
+	}
+	if err := serve(m, r); !errors.Is(err, ErrReplayedSignature) {
+		t.Errorf("Handler() for replayed request error = %v, want %v", err, ErrReplayedSignature)
+	}
+}
@@ -0,0 +1,156 @@
+package httpsign
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ResponseSigner is an HTTP middleware which signs outgoing HTTP responses.
+// The signature is sent in the Signature-Input and Signature fields as specified in RFC 9421.
+type ResponseSigner struct {
+	// ErrorHandler is used to handle errors that occur during response signing.
+	// If not provided, DefaultErrorHandler is used.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	// Components are the derived components covered by the signature, in order.
+	// By default, only the status code is covered.
+	Components []string
+	// Headers are the names of the response header fields covered by the signature, in order.
+	// Fields missing from a response are not covered.
+	Headers []string
+	// DigestAlgorithm is the algorithm used to compute the Content-Digest field (RFC 9530) of responses,
+	// which is then covered by the signature. By default, DigestSHA256 is used.
+	// If empty, the response body is not signed.
+	DigestAlgorithm string
+	// Nonce returns the nonce sent as the nonce signature parameter.
+	// If nil, the parameter is omitted. By default, nonces are not sent.
+	Nonce func() (string, error)
+	// RequestComponents are the derived components and fields of the request covered by the signature
+	// with the req parameter (RFC 9421 Section 2.4), in order, binding the response to the request.
+	// By default, no request components are covered.
+	RequestComponents []string
+	// RequestSignatures reports whether the signatures of the request are covered by the signature
+	// with the req parameter, so that the client can prove the response was issued for its signed request.
+	// If the Signature field of the request is malformed, the response is signed without them.
+	RequestSignatures bool
+	// KeyID is sent as the keyid signature parameter to identify the key used to sign responses.
+	// If empty, the parameter is omitted.
+	KeyID string
+
+	signer Signer
+}
+
+// NewResponseSigner returns a new [ResponseSigner] given a [Signer].
+func NewResponseSigner(signer Signer) *ResponseSigner {
+	return &ResponseSigner{
+		ErrorHandler:    DefaultErrorHandler,
+		Components:      []string{ComponentStatus},
+		DigestAlgorithm: DigestSHA256,
+		signer:          signer,
+	}
+}
+
+// Handler returns a handler that signs the responses of h.
+//
+// The response of h is buffered in memory and sent once h returns,
+// so h cannot stream its response.
+func (s *ResponseSigner) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseRecorder{header: w.Header()}
+		h.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if err := s.sign(r, rec); err != nil {
+			// The fields describing the discarded response body must not be sent with the error.
+			rec.header.Del(contentDigestHeader)
+			rec.header.Del("Content-Length")
+			s.ErrorHandler(w, r, err)
+			return
+		}
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(rec.body.Bytes())
+	})
+}
+
+func (s *ResponseSigner) sign(r *http.Request, rec *responseRecorder) error {
+	digested := s.DigestAlgorithm != "" && bodyAllowed(r, rec.status)
+	if digested {
+		digest, err := contentDigest(s.DigestAlgorithm, rec.body.Bytes())
+		if err != nil {
+			return err
+		}
+		rec.header.Set(contentDigestHeader, digest)
+	}
+	if rec.header.Get("Content-Length") == "" && bodyAllowed(r, rec.status) {
+		rec.header.Set("Content-Length", strconv.Itoa(rec.body.Len()))
+	}
+
+	headers, err := headerComponents(rec.header, s.Headers, MissingHeaderSkip)
+	if err != nil {
+		return err
+	}
+	ids := componentIDs(s.Components, false)
+	if id := (componentID{name: "content-digest"}); digested && !slices.Contains(ids, id) && !slices.Contains(headers, id) {
+		ids = append(ids, id)
+	}
+	ids = append(ids, headers...)
+	ids = append(ids, componentIDs(s.RequestComponents, true)...)
+	if s.RequestSignatures {
+		// Malformed request signatures cannot be bound to, so the response is signed without them.
+		sigs, _ := parseDictionaryField(r.Header, signatureHeader)
+		for _, sig := range sigs {
+			ids = append(ids, componentID{name: "signature", key: sig.Key, req: true})
+		}
+	}
+
+	nonce, err := newNonce(s.Nonce)
+	if err != nil {
+		return err
+	}
+	m := message{req: r, status: rec.status, header: rec.header}
+	return signMessage(r.Context(), s.signer, m, DefaultLabel, signatureParams{
+		components: ids,
+		created:    time.Now(),
+		nonce:      nonce,
+		keyID:      s.KeyID,
+		alg:        algorithm(s.signer),
+	})
+}
+
+// bodyAllowed reports whether a response with the status code to the request has content.
+func bodyAllowed(r *http.Request, status int) bool {
+	switch {
+	case r.Method == http.MethodHead:
+		return false
+	case status >= 100 && status <= 199, status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	default:
+		return true
+	}
+}
+
+// responseRecorder is an [http.ResponseWriter] which buffers the status code and body of a response.
+// The header is shared with the underlying ResponseWriter.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header { return rec.header }
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
@@ -0,0 +1,309 @@
+package httpsign
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+// verifyResponse verifies the stub signature of the recorded response.
+func verifyResponse(t *testing.T, r *http.Request, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	inputs, err := parseDictionaryField(w.Header(), signatureInputHeader)
+	if err != nil || len(inputs) != 1 {
+		t.Fatalf("%s = %q, error: %v", signatureInputHeader, w.Header().Values(signatureInputHeader), err)
+	}
+	sigs, err := parseDictionaryField(w.Header(), signatureHeader)
+	if err != nil {
+		t.Fatalf("%s = %q, error: %v", signatureHeader, w.Header().Values(signatureHeader), err)
+	}
+	params := inputs[0].Value.(structfield.InnerList)
+	sp, err := parseSignatureParams(params)
+	if err != nil {
+		t.Fatalf("parseSignatureParams() error: %v", err)
+	}
+	base, err := signatureBase(message{req: r, status: w.Code, header: w.Header()}, params)
+	if err != nil {
+		t.Fatalf("signatureBase() error: %v", err)
+	}
+	sig, _ := sigs.Get(inputs[0].Key)
+	if got := sig.(structfield.Item).Value.([]byte); string(got) != string(base) {
+		t.Errorf("Signature = %q, want %q", got, base)
+	}
+	names := make([]string, len(sp.components))
+	for i, c := range sp.components {
+		names[i] = c.String()
+	}
+	return names
+}
+
+func TestResponseSigner(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		status     int
+		headers    []string
+		body       string
+		components []string
+	}{
+		{"ok", "GET", 0, nil, "hello", []string{`"@status"`, `"content-digest"`}},
+		{"created", "POST", http.StatusCreated, []string{"Content-Type", "X-Missing"}, "hello", []string{`"@status"`, `"content-digest"`, `"content-type"`}},
+		{"covered digest", "GET", 0, []string{"Content-Digest"}, "hello", []string{`"@status"`, `"content-digest"`}},
+		{"no content", "DELETE", http.StatusNoContent, nil, "", []string{`"@status"`}},
+		{"head", "HEAD", http.StatusOK, nil, "", []string{`"@status"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewResponseSigner(stubSigner{})
+			s.Headers = tt.headers
+			h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+
+			r := httptest.NewRequest(tt.method, "http://example.com/", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if want := max(tt.status, http.StatusOK); w.Code != want {
+				t.Errorf("code: %d, want %d", w.Code, want)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+			components := verifyResponse(t, r, w)
+			if fmt.Sprint(components) != fmt.Sprint(tt.components) {
+				t.Errorf("components = %q, want %q", components, tt.components)
+			}
+			if len(w.Header().Values(contentDigestHeader)) > 0 {
+				if err := verifyContentDigest(w.Header(), w.Body.Bytes()); err != nil {
+					t.Errorf("verifyContentDigest() error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseSigner_Error(t *testing.T) {
+	s := NewResponseSigner(stubSigner{})
+	s.Components = []string{ComponentMethod}
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("code: %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	for _, name := range []string{contentDigestHeader, "Content-Length"} {
+		if v := w.Header().Get(name); v != "" {
+			t.Errorf("%s = %q of the discarded response", name, v)
+		}
+	}
+}
+
+func TestResponseSigner_MalformedRequestSignature(t *testing.T) {
+	s := NewResponseSigner(stubSigner{})
+	s.RequestSignatures = true
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.Header.Set(signatureHeader, "garbage(")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("response: %d %q, want %d %q", w.Code, w.Body.String(), http.StatusOK, "hello")
+	}
+	want := []string{`"@status"`, `"content-digest"`}
+	if components := verifyResponse(t, r, w); fmt.Sprint(components) != fmt.Sprint(want) {
+		t.Errorf("components = %q, want %q", components, want)
+	}
+}
+
+func TestTransport_ResponseVerification(t *testing.T) {
+	h := NewResponseSigner(stubSigner{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	s := httptest.NewServer(h)
+	defer s.Close()
+	unsigned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer unsigned.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		tamper  func(resp *http.Response)
+		reason  Reason // of the RoundTrip error
+		bodyErr Reason // of the error reading the body
+	}{
+		{name: "valid", url: s.URL},
+		{name: "unsigned", url: unsigned.URL, reason: ReasonMissingHeader},
+		{name: "status", url: s.URL, tamper: func(resp *http.Response) {
+			resp.StatusCode = http.StatusCreated
+		}, reason: ReasonSignatureMismatch},
+		{name: "body", url: s.URL, tamper: func(resp *http.Response) {
+			resp.Body = io.NopCloser(strings.NewReader("jello"))
+		}, bodyErr: ReasonDigestMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			tr.ResponseResolver = SingleKey(stubVerifier{})
+			tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				resp, err := http.DefaultTransport.RoundTrip(r)
+				if err == nil && tt.tamper != nil {
+					tt.tamper(resp)
+				}
+				return resp, err
+			})
+			c := http.Client{Transport: tr}
+
+			resp, err := c.Get(tt.url)
+			var verr *VerificationError
+			if tt.reason != 0 {
+				if !errors.As(err, &verr) || verr.Reason != tt.reason {
+					t.Fatalf("Get() error = %v, want reason %v", err, tt.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if tt.bodyErr != 0 {
+				if !errors.As(err, &verr) || verr.Reason != tt.bodyErr {
+					t.Fatalf("ReadAll() error = %v, want reason %v", err, tt.bodyErr)
+				}
+				return
+			}
+			if err != nil || string(body) != "hello" {
+				t.Errorf("ReadAll() = %q, %v, want %q", body, err, "hello")
+			}
+		})
+	}
+}
+
+func TestTransport_ResponseFreshness(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	signed := httptest.NewServer(NewResponseSigner(stubSigner{}).Handler(handler))
+	defer signed.Close()
+	uncreated := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sp := signatureParams{components: componentIDs([]string{ComponentStatus}, false)}
+		if err := signMessage(r.Context(), stubSigner{}, message{req: r, status: http.StatusOK, header: w.Header()}, DefaultLabel, sp); err != nil {
+			t.Errorf("signMessage() error: %v", err)
+		}
+	}))
+	defer uncreated.Close()
+	later := func() time.Time { return time.Now().Add(time.Hour) }
+	earlier := func() time.Time { return time.Now().Add(-2 * DefaultMaxSkew) }
+
+	tests := []struct {
+		name   string
+		url    string
+		config func(tr *Transport)
+		reason Reason
+	}{
+		{name: "fresh", url: signed.URL},
+		{name: "old", url: signed.URL, config: func(tr *Transport) { tr.Now = later }, reason: ReasonExpired},
+		{name: "old within max age", url: signed.URL, config: func(tr *Transport) {
+			tr.Now, tr.ResponseMaxAge = later, 2*time.Hour
+		}},
+		{name: "future", url: signed.URL, config: func(tr *Transport) { tr.Now = earlier }, reason: ReasonNotYetValid},
+		{name: "future within max skew", url: signed.URL, config: func(tr *Transport) {
+			tr.Now, tr.ResponseMaxSkew = earlier, 3*DefaultMaxSkew
+		}},
+		{name: "no created", url: uncreated.URL, reason: ReasonMissingParameter},
+		{name: "no created without max age", url: uncreated.URL, config: func(tr *Transport) { tr.ResponseMaxAge = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			tr.ResponseResolver = SingleKey(stubVerifier{})
+			if tt.config != nil {
+				tt.config(tr)
+			}
+			c := http.Client{Transport: tr}
+
+			resp, err := c.Get(tt.url)
+			if tt.reason == 0 {
+				if err != nil {
+					t.Fatalf("Get() error: %v", err)
+				}
+				resp.Body.Close()
+				return
+			}
+			var verr *VerificationError
+			if !errors.As(err, &verr) || verr.Reason != tt.reason {
+				t.Fatalf("Get() error = %v, want reason %v", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestTransport_BoundResponse(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	})
+	bound := NewResponseSigner(stubSigner{})
+	bound.RequestComponents = []string{ComponentMethod, ComponentPath}
+	bound.RequestSignatures = true
+	s := httptest.NewServer(bound.Handler(handler))
+	defer s.Close()
+	unbound := httptest.NewServer(NewResponseSigner(stubSigner{}).Handler(handler))
+	defer unbound.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		forge  bool // whether the response is verified against another request signature
+		reason Reason
+	}{
+		{name: "bound", url: s.URL},
+		{name: "unbound", url: unbound.URL, reason: ReasonMissingComponent},
+		{name: "other request", url: s.URL, forge: true, reason: ReasonSignatureMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(stubSigner{})
+			tr.ResponseResolver = SingleKey(stubVerifier{})
+			tr.RequireBoundResponse = true
+			tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				resp, err := http.DefaultTransport.RoundTrip(r)
+				if tt.forge {
+					r.Header.Set(signatureHeader, DefaultLabel+"=:AAEC:")
+				}
+				return resp, err
+			})
+			c := http.Client{Transport: tr}
+
+			resp, err := c.Get(tt.url + "/foo")
+			if tt.reason == 0 {
+				if err != nil {
+					t.Fatalf("Get() error: %v", err)
+				}
+				resp.Body.Close()
+				return
+			}
+			var verr *VerificationError
+			if !errors.As(err, &verr) || verr.Reason != tt.reason {
+				t.Fatalf("Get() error = %v, want reason %v", err, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package httpsign
+
+import (
+	"context"
+	"time"
+)
+
+// Result is the result of the successful verification of a signature.
+type Result struct {
+	// Label is the label of the signature.
+	Label string
+	// KeyID is the keyid signature parameter, if present.
+	KeyID string
+	// Algorithm is the alg signature parameter or, if absent, the algorithm of the Verifier, if known.
+	Algorithm string
+	// Components are the serialized identifiers of the components covered by the signature, in order,
+	// with their parameters, such as "@method" or "signature";key="sig1";req, quotes included.
+	Components []string
+	// Created is the creation time of the signature.
+	Created time.Time
+	// Expires is the expiration time of the signature, or the zero time if it has no expires parameter.
+	Expires time.Time
+}
+
+type contextKey struct{}
+
+// FromContext returns the results of the signatures verified by a [Middleware]
+// stored in the request context, in the order of the Signature-Input field.
+// It reports false if the request was not verified.
+func FromContext(ctx context.Context) ([]Result, bool) {
+	results, ok := ctx.Value(contextKey{}).([]Result)
+	return results, ok
+}
+
+// newContext returns a copy of ctx storing the results of the verified signatures.
+func newContext(ctx context.Context, results []Result) context.Context {
+	return context.WithValue(ctx, contextKey{}, results)
+}
@@ -0,0 +1,51 @@
+package httpsign
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFromContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Errorf("FromContext(context.Background()) ok = true, want false")
+	}
+
+	tr := NewTransport(algSigner{stubSigner{}, AlgorithmEd25519})
+	tr.KeyID = "a"
+	tr.TTL = time.Minute
+	r := signedRequest(t, tr, httptest.NewRequest("GET", "http://example.com/", nil))
+	// A second signature covers the first one.
+	sp := signatureParams{components: []componentID{{name: "signature", key: DefaultLabel}}, created: time.Now(), keyID: "a"}
+	if err := signMessage(r.Context(), stubSigner{}, requestMessage(r), "proxy", sp); err != nil {
+		t.Fatalf("signMessage() error: %v", err)
+	}
+
+	var results []Result
+	var ok bool
+	m := NewResolverMiddleware(Keys{"a": stubVerifier{}})
+	m.ErrorHandler = loggingErrorHandler(t)
+	m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results, ok = FromContext(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if !ok || len(results) != 2 {
+		t.Fatalf("FromContext() = %+v, %t, want 2 results", results, ok)
+	}
+	res := results[0]
+	if res.Label != DefaultLabel || res.KeyID != "a" || res.Algorithm != AlgorithmEd25519 {
+		t.Errorf("FromContext() = %+v, want label %q, keyid %q, alg %q", res, DefaultLabel, "a", AlgorithmEd25519)
+	}
+	if want := []string{`"@method"`, `"@authority"`, `"@path"`, `"@query"`}; fmt.Sprint(res.Components) != fmt.Sprint(want) {
+		t.Errorf("Components = %q, want %q", res.Components, want)
+	}
+	if time.Since(res.Created) > time.Minute || res.Expires.Sub(res.Created) != time.Minute {
+		t.Errorf("Created = %v, Expires = %v", res.Created, res.Expires)
+	}
+	if want := []string{`"signature";key="sig1"`}; fmt.Sprint(results[1].Components) != fmt.Sprint(want) {
+		t.Errorf("Components = %q, want %q", results[1].Components, want)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"io"
+
+	"github.com/denpeshkov/httpsign"
 )
 
 // PKCSSigner signs messages using RSA-PKCS #1 v1.5.
@@ -13,24 +15,34 @@ type PKCSSigner struct {
 	PKCSVerifier
 	Rand io.Reader // Defaults to crypto/rand.Reader if not set.
 
-	priv *rsa.PrivateKey
+	priv crypto.Signer
 }
 
 // NewPKCSSigner returns a new [PKCSSigner] for the provided private key and hash algorithm.
 func NewPKCSSigner(priv *rsa.PrivateKey, hash crypto.Hash) (*PKCSSigner, error) {
+	return NewPKCSCryptoSigner(priv, hash)
+}
+
+// NewPKCSCryptoSigner returns a new [PKCSSigner] for the provided [crypto.Signer] and hash algorithm.
+// The public key of the signer must be an *rsa.PublicKey.
+func NewPKCSCryptoSigner(signer crypto.Signer, hash crypto.Hash) (*PKCSSigner, error) {
 	if !hash.Available() {
 		return nil, ErrHashUnavailable
 	}
+	pub, err := publicKey(signer)
+	if err != nil {
+		return nil, err
+	}
 	return &PKCSSigner{
 		Rand:         rand.Reader,
-		priv:         priv,
-		PKCSVerifier: PKCSVerifier{pub: &priv.PublicKey, hash: hash},
+		priv:         signer,
+		PKCSVerifier: PKCSVerifier{pub: pub, hash: hash},
 	}, nil
 }
 
 // Sign signs a message using the private key.
 func (s *PKCSSigner) Sign(message []byte) ([]byte, error) {
-	return rsa.SignPKCS1v15(s.Rand, s.priv, s.hash, s.digest(message))
+	return s.priv.Sign(s.Rand, s.digest(message), s.hash)
 }
 
 // PKCSVerifier verifies RSA-PKCS #1 v1.5 message signatures.
@@ -50,10 +62,24 @@ func NewPKCSVerifier(pub *rsa.PublicKey, hash crypto.Hash) (*PKCSVerifier, error
 
 // Verify verifies the signature of a message using the public key.
 func (v *PKCSVerifier) Verify(message []byte, signature []byte) (bool, error) {
-	if err := rsa.VerifyPKCS1v15(v.pub, v.hash, v.digest(message), signature); err != nil {
+	if err := checkSize(v.pub, signature); err != nil {
 		return false, err
 	}
-	return true, nil
+	return rsa.VerifyPKCS1v15(v.pub, v.hash, v.digest(message), signature) == nil, nil
+}
+
+// Algorithm returns the HTTP Signature Algorithms registry name of the algorithm,
+// or an empty string if the hash algorithm has no registered name.
+func (v *PKCSVerifier) Algorithm() string {
+	if v.hash == crypto.SHA256 {
+		return httpsign.AlgorithmRSAPKCSSHA256
+	}
+	return ""
+}
+
+// Public returns the public key.
+func (v *PKCSVerifier) Public() crypto.PublicKey {
+	return v.pub
 }
 
 func (v *PKCSVerifier) digest(msg []byte) []byte {
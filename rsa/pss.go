@@ -2,14 +2,22 @@
 package rsa
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"errors"
+	"fmt"
 	"io"
+
+	"github.com/denpeshkov/httpsign"
 )
 
-// ErrHashUnavailable is returned when the hash function is not linked into the binary.
-var ErrHashUnavailable = errors.New("rsa: requested hash function is unavailable")
+var (
+	// ErrHashUnavailable is returned when the hash function is not linked into the binary.
+	ErrHashUnavailable = errors.New("rsa: requested hash function is unavailable")
+	// ErrUnsupportedKey is returned when the public key of a [crypto.Signer] is not an RSA key.
+	ErrUnsupportedKey = errors.New("rsa: public key is not an RSA key")
+)
 
 // PSSSigner signs messages using RSA-PSS.
 // It is safe for concurrent use by multiple goroutines.
@@ -17,24 +25,34 @@ type PSSSigner struct {
 	PSSVerifier
 	Rand io.Reader // Defaults to crypto/rand.Reader if not set.
 
-	priv *rsa.PrivateKey
+	priv crypto.Signer
 }
 
 // NewPSSSigner returns a new [PSSSigner] for the provided private key.
 func NewPSSSigner(priv *rsa.PrivateKey, opts *rsa.PSSOptions) (*PSSSigner, error) {
+	return NewPSSCryptoSigner(priv, opts)
+}
+
+// NewPSSCryptoSigner returns a new [PSSSigner] for the provided [crypto.Signer].
+// The public key of the signer must be an *rsa.PublicKey, and the signer must support *rsa.PSSOptions.
+func NewPSSCryptoSigner(signer crypto.Signer, opts *rsa.PSSOptions) (*PSSSigner, error) {
 	if !opts.Hash.Available() {
 		return nil, ErrHashUnavailable
 	}
+	pub, err := publicKey(signer)
+	if err != nil {
+		return nil, err
+	}
 	return &PSSSigner{
 		Rand:        rand.Reader,
-		priv:        priv,
-		PSSVerifier: PSSVerifier{pub: &priv.PublicKey, opts: opts},
+		priv:        signer,
+		PSSVerifier: PSSVerifier{pub: pub, opts: opts},
 	}, nil
 }
 
 // Sign signs a message using the private key.
 func (s *PSSSigner) Sign(message []byte) ([]byte, error) {
-	return rsa.SignPSS(s.Rand, s.priv, s.opts.Hash, s.digest(message), s.opts)
+	return s.priv.Sign(s.Rand, s.digest(message), s.opts)
 }
 
 // PSSVerifier verifies RSA-PSS message signatures.
@@ -54,10 +72,25 @@ func NewPSSVerifier(pub *rsa.PublicKey, opts *rsa.PSSOptions) (*PSSVerifier, err
 
 // Verify verifies the signature of a message using the public key.
 func (v *PSSVerifier) Verify(message []byte, signature []byte) (bool, error) {
-	if err := rsa.VerifyPSS(v.pub, v.opts.Hash, v.digest(message), signature, v.opts); err != nil {
+	if err := checkSize(v.pub, signature); err != nil {
 		return false, err
 	}
-	return true, nil
+	return rsa.VerifyPSS(v.pub, v.opts.Hash, v.digest(message), signature, v.opts) == nil, nil
+}
+
+// Algorithm returns the HTTP Signature Algorithms registry name of the algorithm,
+// or an empty string if the options have no registered name.
+// RFC 9421 registers RSA-PSS only with SHA-512 and a 64-byte salt.
+func (v *PSSVerifier) Algorithm() string {
+	if v.opts.Hash == crypto.SHA512 && (v.opts.SaltLength == rsa.PSSSaltLengthEqualsHash || v.opts.SaltLength == 64) {
+		return httpsign.AlgorithmRSAPSSSHA512
+	}
+	return ""
+}
+
+// Public returns the public key.
+func (v *PSSVerifier) Public() crypto.PublicKey {
+	return v.pub
 }
 
 func (v *PSSVerifier) digest(msg []byte) []byte {
@@ -65,3 +98,20 @@ func (v *PSSVerifier) digest(msg []byte) []byte {
 	_, _ = h.Write(msg) // never returns an error
 	return h.Sum(nil)
 }
+
+// publicKey returns the RSA public key of the signer.
+func publicKey(signer crypto.Signer) (*rsa.PublicKey, error) {
+	pub, ok := signer.Public().(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, signer.Public())
+	}
+	return pub, nil
+}
+
+// checkSize checks that the signature has the size of the public key modulus.
+func checkSize(pub *rsa.PublicKey, signature []byte) error {
+	if len(signature) != pub.Size() {
+		return fmt.Errorf("rsa: signature length %d, want %d: %w", len(signature), pub.Size(), httpsign.ErrMalformedSignature)
+	}
+	return nil
+}
@@ -2,10 +2,15 @@ package rsa
 
 import (
 	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
+	_ "crypto/sha256"
 	_ "crypto/sha512"
+	"errors"
 	"testing"
+
+	"github.com/denpeshkov/httpsign"
 )
 
 func TestSignVerify_PSS(t *testing.T) {
@@ -103,3 +108,95 @@ func TestSignVerify_PKCS(t *testing.T) {
 		testf()
 	})
 }
+
+func TestVerify_Invalid(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	pss, err := NewPSSSigner(key, &rsa.PSSOptions{Hash: crypto.SHA512, SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		t.Fatalf("NewPSSSigner() error: %v", err)
+	}
+	pkcs, err := NewPKCSSigner(key, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("NewPKCSSigner() error: %v", err)
+	}
+
+	msg := []byte("test")
+	for _, sig := range []interface {
+		Sign(message []byte) ([]byte, error)
+		Verify(message []byte, signature []byte) (bool, error)
+	}{pss, pkcs} {
+		sign, err := sig.Sign(msg)
+		if err != nil {
+			t.Fatalf("Sign(%s) error: %v", msg, err)
+		}
+		if ok, err := sig.Verify([]byte("forged"), sign); ok || err != nil {
+			t.Errorf("%T.Verify(forged, %x) = %t, %v; want false, nil", sig, sign, ok, err)
+		}
+		if ok, err := sig.Verify(msg, sign[1:]); ok || !errors.Is(err, httpsign.ErrMalformedSignature) {
+			t.Errorf("%T.Verify(%s, %x) = %t, %v; want false, %v", sig, msg, sign[1:], ok, err, httpsign.ErrMalformedSignature)
+		}
+	}
+}
+
+// opaqueSigner is a crypto.Signer without access to its private key.
+type opaqueSigner struct{ crypto.Signer }
+
+func TestCryptoSigner(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	pssOpts := &rsa.PSSOptions{Hash: crypto.SHA512, SaltLength: rsa.PSSSaltLengthEqualsHash}
+
+	pkcs, err := NewPKCSCryptoSigner(opaqueSigner{key}, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("NewPKCSCryptoSigner() error: %v", err)
+	}
+	pss, err := NewPSSCryptoSigner(opaqueSigner{key}, pssOpts)
+	if err != nil {
+		t.Fatalf("NewPSSCryptoSigner() error: %v", err)
+	}
+	pkcsVer, err := NewPKCSVerifier(&key.PublicKey, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("NewPKCSVerifier() error: %v", err)
+	}
+	pssVer, err := NewPSSVerifier(&key.PublicKey, pssOpts)
+	if err != nil {
+		t.Fatalf("NewPSSVerifier() error: %v", err)
+	}
+
+	msg := []byte("test")
+	for _, tt := range []struct {
+		name string
+		sig  httpsign.Signer
+		ver  httpsign.Verifier
+	}{
+		{"PKCS", pkcs, pkcsVer},
+		{"PSS", pss, pssVer},
+	} {
+		sign, err := tt.sig.Sign(msg)
+		if err != nil {
+			t.Fatalf("%s Signer.Sign(%s) error: %v", tt.name, msg, err)
+		}
+		if ok, err := tt.ver.Verify(msg, sign); err != nil || !ok {
+			t.Errorf("%s signed message not verified by Verifier: %v", tt.name, err)
+		}
+		if pub := tt.sig.(interface{ Public() crypto.PublicKey }).Public(); !key.PublicKey.Equal(pub) {
+			t.Errorf("%s Signer.Public() = %v, want %v", tt.name, pub, &key.PublicKey)
+		}
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	if _, err := NewPKCSCryptoSigner(priv, crypto.SHA256); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("NewPKCSCryptoSigner(Ed25519 key) error = %v, want %v", err, ErrUnsupportedKey)
+	}
+	if _, err := NewPSSCryptoSigner(priv, pssOpts); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("NewPSSCryptoSigner(Ed25519 key) error = %v, want %v", err, ErrUnsupportedKey)
+	}
+}
@@ -0,0 +1,343 @@
+package structfield
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseDictionary parses a dictionary field value (RFC 8941 Section 4.2.2).
+// A field with multiple lines must be combined with commas before parsing.
+func ParseDictionary(s string) (Dictionary, error) {
+	p := &parser{s: s}
+	p.skipSP()
+	var d Dictionary
+	for !p.eof() {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var value Member
+		if p.peek() == '=' {
+			p.i++
+			if value, err = p.member(); err != nil {
+				return nil, err
+			}
+		} else {
+			params, err := p.params()
+			if err != nil {
+				return nil, err
+			}
+			value = Item{Value: true, Params: params}
+		}
+		d = d.Set(key, value)
+		if err := p.next("dictionary"); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// ParseList parses a list field value (RFC 8941 Section 4.2.1).
+// A field with multiple lines must be combined with commas before parsing.
+func ParseList(s string) (List, error) {
+	p := &parser{s: s}
+	p.skipSP()
+	var l List
+	for !p.eof() {
+		m, err := p.member()
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, m)
+		if err := p.next("list"); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// ParseItem parses an item field value (RFC 8941 Section 4.2.3).
+func ParseItem(s string) (Item, error) {
+	p := &parser{s: s}
+	p.skipSP()
+	it, err := p.item()
+	if err != nil {
+		return Item{}, err
+	}
+	p.skipSP()
+	if !p.eof() {
+		return Item{}, p.errorf("unexpected trailing characters")
+	}
+	return it, nil
+}
+
+// parser implements the parsing algorithms of RFC 8941 Section 4.2.
+type parser struct {
+	s string
+	i int
+}
+
+// next consumes the separator between list or dictionary members.
+func (p *parser) next(kind string) error {
+	p.skipOWS()
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != ',' {
+		return p.errorf("expected ',' after %s member", kind)
+	}
+	p.i++
+	p.skipOWS()
+	if p.eof() {
+		return p.errorf("trailing ',' in %s", kind)
+	}
+	return nil
+}
+
+func (p *parser) member() (Member, error) {
+	if p.peek() == '(' {
+		return p.innerList()
+	}
+	return p.item()
+}
+
+func (p *parser) innerList() (InnerList, error) {
+	p.i++ // '('
+	var l InnerList
+	for !p.eof() {
+		p.skipSP()
+		if p.peek() == ')' {
+			p.i++
+			params, err := p.params()
+			if err != nil {
+				return InnerList{}, err
+			}
+			l.Params = params
+			return l, nil
+		}
+		it, err := p.item()
+		if err != nil {
+			return InnerList{}, err
+		}
+		l.Items = append(l.Items, it)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return InnerList{}, p.errorf("expected ' ' or ')' in inner list")
+		}
+	}
+	return InnerList{}, p.errorf("unterminated inner list")
+}
+
+func (p *parser) item() (Item, error) {
+	v, err := p.bareItem()
+	if err != nil {
+		return Item{}, err
+	}
+	params, err := p.params()
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Value: v, Params: params}, nil
+}
+
+func (p *parser) params() (Params, error) {
+	var ps Params
+	for p.peek() == ';' {
+		p.i++
+		p.skipSP()
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var value any = true
+		if p.peek() == '=' {
+			p.i++
+			if value, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		replaced := false
+		for i := range ps {
+			if ps[i].Key == key {
+				ps[i].Value, replaced = value, true
+			}
+		}
+		if !replaced {
+			ps = append(ps, Param{Key: key, Value: value})
+		}
+	}
+	return ps, nil
+}
+
+func (p *parser) key() (string, error) {
+	start := p.i
+	if c := p.peek(); !isLCAlpha(c) && c != '*' {
+		return "", p.errorf("invalid key")
+	}
+	for p.i++; !p.eof() && isKeyChar(p.s[p.i]); p.i++ {
+	}
+	return p.s[start:p.i], nil
+}
+
+func (p *parser) bareItem() (any, error) {
+	switch c := p.peek(); {
+	case c == '-' || isDigit(c):
+		return p.number()
+	case c == '"':
+		return p.string()
+	case c == '*' || isAlpha(c):
+		return p.token(), nil
+	case c == ':':
+		return p.byteSequence()
+	case c == '?':
+		return p.boolean()
+	default:
+		return nil, p.errorf("invalid bare item")
+	}
+}
+
+func (p *parser) number() (any, error) {
+	start := p.i
+	if p.peek() == '-' {
+		p.i++
+	}
+	if !isDigit(p.peek()) {
+		return nil, p.errorf("invalid number")
+	}
+	digits, dot := p.i, -1
+	for ; !p.eof(); p.i++ {
+		c := p.s[p.i]
+		if c == '.' && dot < 0 {
+			if p.i-digits > 12 {
+				return nil, p.errorf("decimal integer part too long")
+			}
+			dot = p.i
+			continue
+		}
+		if !isDigit(c) {
+			break
+		}
+		if dot < 0 && p.i-digits >= 15 {
+			return nil, p.errorf("integer too long")
+		}
+		if dot >= 0 && p.i-digits >= 16 {
+			return nil, p.errorf("decimal too long")
+		}
+	}
+	num := p.s[start:p.i]
+	if dot < 0 {
+		return strconv.ParseInt(num, 10, 64)
+	}
+	if frac := p.i - dot - 1; frac < 1 || frac > 3 {
+		return nil, p.errorf("invalid decimal fraction")
+	}
+	return strconv.ParseFloat(num, 64)
+}
+
+func (p *parser) string() (string, error) {
+	p.i++ // '"'
+	var b strings.Builder
+	for !p.eof() {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == '\\':
+			if p.eof() || (p.s[p.i] != '"' && p.s[p.i] != '\\') {
+				return "", p.errorf("invalid string escape")
+			}
+			b.WriteByte(p.s[p.i])
+			p.i++
+		case c == '"':
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", p.errorf("invalid string character")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) token() Token {
+	start := p.i
+	for p.i++; !p.eof() && isTokenChar(p.s[p.i]); p.i++ {
+	}
+	return Token(p.s[start:p.i])
+}
+
+func (p *parser) byteSequence() ([]byte, error) {
+	p.i++ // ':'
+	end := strings.IndexByte(p.s[p.i:], ':')
+	if end < 0 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+	s := p.s[p.i : p.i+end]
+	for i := 0; i < len(s); i++ {
+		if !isBase64Char(s[i]) {
+			return nil, p.errorf("invalid byte sequence character")
+		}
+	}
+	// Parsers should not fail when "=" padding is not present (RFC 8941 Section 4.2.7).
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, p.errorf("invalid byte sequence: %v", err)
+	}
+	p.i += end + 1
+	return b, nil
+}
+
+func (p *parser) boolean() (bool, error) {
+	p.i++ // '?'
+	switch p.peek() {
+	case '1':
+		p.i++
+		return true, nil
+	case '0':
+		p.i++
+		return false, nil
+	default:
+		return false, p.errorf("invalid boolean")
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *parser) eof() bool { return p.i >= len(p.s) }
+
+func (p *parser) skipSP() {
+	for p.peek() == ' ' {
+		p.i++
+	}
+}
+
+func (p *parser) skipOWS() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.i++
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, p.i, fmt.Sprintf(format, args...))
+}
+
+func isLCAlpha(c byte) bool { return 'a' <= c && c <= 'z' }
+func isAlpha(c byte) bool   { return isLCAlpha(c) || 'A' <= c && c <= 'Z' }
+func isDigit(c byte) bool   { return '0' <= c && c <= '9' }
+
+func isKeyChar(c byte) bool {
+	return isLCAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '.' || c == '*'
+}
+
+func isTokenChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
+
+func isBase64Char(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '+' || c == '/' || c == '='
+}
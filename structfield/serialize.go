@@ -0,0 +1,205 @@
+package structfield
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Serialize serializes the dictionary (RFC 8941 Section 4.1.2).
+func (d Dictionary) Serialize() (string, error) {
+	var b strings.Builder
+	for i, m := range d {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := writeKey(&b, m.Key); err != nil {
+			return "", err
+		}
+		if it, ok := m.Value.(Item); ok && it.Value == true {
+			if err := it.Params.write(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte('=')
+		if err := writeMember(&b, m.Value); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// Serialize serializes the list (RFC 8941 Section 4.1.1).
+func (l List) Serialize() (string, error) {
+	var b strings.Builder
+	for i, m := range l {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := writeMember(&b, m); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// Serialize serializes the inner list (RFC 8941 Section 4.1.1.1).
+func (l InnerList) Serialize() (string, error) {
+	var b strings.Builder
+	if err := l.write(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Serialize serializes the item (RFC 8941 Section 4.1.3).
+func (it Item) Serialize() (string, error) {
+	var b strings.Builder
+	if err := it.write(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func writeMember(b *strings.Builder, m Member) error {
+	switch m := m.(type) {
+	case Item:
+		return m.write(b)
+	case InnerList:
+		return m.write(b)
+	default:
+		return fmt.Errorf("%w: member of type %T", ErrInvalidValue, m)
+	}
+}
+
+func (l InnerList) write(b *strings.Builder) error {
+	b.WriteByte('(')
+	for i, it := range l.Items {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if err := it.write(b); err != nil {
+			return err
+		}
+	}
+	b.WriteByte(')')
+	return l.Params.write(b)
+}
+
+func (it Item) write(b *strings.Builder) error {
+	if err := writeBareItem(b, it.Value); err != nil {
+		return err
+	}
+	return it.Params.write(b)
+}
+
+func (ps Params) write(b *strings.Builder) error {
+	for _, p := range ps {
+		b.WriteByte(';')
+		if err := writeKey(b, p.Key); err != nil {
+			return err
+		}
+		if p.Value != true {
+			b.WriteByte('=')
+			if err := writeBareItem(b, p.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeKey(b *strings.Builder, key string) error {
+	if key == "" || (!isLCAlpha(key[0]) && key[0] != '*') {
+		return fmt.Errorf("%w: key %q", ErrInvalidValue, key)
+	}
+	for i := 1; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return fmt.Errorf("%w: key %q", ErrInvalidValue, key)
+		}
+	}
+	b.WriteString(key)
+	return nil
+}
+
+func writeBareItem(b *strings.Builder, v any) error {
+	switch v := v.(type) {
+	case int:
+		return writeInteger(b, int64(v))
+	case int64:
+		return writeInteger(b, v)
+	case float64:
+		return writeDecimal(b, v)
+	case string:
+		return writeString(b, v)
+	case Token:
+		return writeToken(b, v)
+	case []byte:
+		b.WriteByte(':')
+		b.WriteString(base64.StdEncoding.EncodeToString(v))
+		b.WriteByte(':')
+		return nil
+	case bool:
+		if v {
+			b.WriteString("?1")
+		} else {
+			b.WriteString("?0")
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: bare item of type %T", ErrInvalidValue, v)
+	}
+}
+
+func writeInteger(b *strings.Builder, v int64) error {
+	if v < -999_999_999_999_999 || v > 999_999_999_999_999 {
+		return fmt.Errorf("%w: integer %d out of range", ErrInvalidValue, v)
+	}
+	b.WriteString(strconv.FormatInt(v, 10))
+	return nil
+}
+
+func writeDecimal(b *strings.Builder, v float64) error {
+	v = math.RoundToEven(v*1000) / 1000
+	if math.IsNaN(v) || math.Abs(v) >= 1e12 {
+		return fmt.Errorf("%w: decimal %v out of range", ErrInvalidValue, v)
+	}
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	b.WriteString(s)
+	return nil
+}
+
+func writeString(b *strings.Builder, v string) error {
+	b.WriteByte('"')
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c < 0x20 || c > 0x7e {
+			return fmt.Errorf("%w: string %q", ErrInvalidValue, v)
+		}
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+	return nil
+}
+
+func writeToken(b *strings.Builder, v Token) error {
+	if v == "" || (!isAlpha(v[0]) && v[0] != '*') {
+		return fmt.Errorf("%w: token %q", ErrInvalidValue, v)
+	}
+	for i := 1; i < len(v); i++ {
+		if !isTokenChar(v[i]) {
+			return fmt.Errorf("%w: token %q", ErrInvalidValue, v)
+		}
+	}
+	b.WriteString(string(v))
+	return nil
+}
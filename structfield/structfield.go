@@ -0,0 +1,95 @@
+// Package structfield implements Structured Field Values for HTTP (RFC 8941).
+//
+// Bare items are represented by the following Go types:
+//
+//   - Integer: int64
+//   - Decimal: float64
+//   - String: string
+//   - Token: [Token]
+//   - Byte Sequence: []byte
+//   - Boolean: bool
+//
+// Parsing is strict: any input that RFC 8941 requires parsers to fail on is rejected.
+// Serialization is canonical: parsing and then serializing a valid field value
+// produces the same value regardless of optional whitespace in the input.
+package structfield
+
+import "errors"
+
+var (
+	// ErrSyntax is returned when parsing a malformed field value.
+	ErrSyntax = errors.New("structfield: invalid syntax")
+	// ErrInvalidValue is returned when serializing a value that cannot be represented as a structured field.
+	ErrInvalidValue = errors.New("structfield: invalid value")
+)
+
+// Token is a Structured Field token (RFC 8941 Section 3.3.4).
+type Token string
+
+// Param is a single parameter of an item or inner list (RFC 8941 Section 3.1.2).
+// Value is a bare item.
+type Param struct {
+	Key   string
+	Value any
+}
+
+// Params are ordered parameters.
+type Params []Param
+
+// Get returns the value of the parameter with the given key.
+func (ps Params) Get(key string) (any, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Item is a Structured Field item: a bare item with parameters (RFC 8941 Section 3.3).
+type Item struct {
+	Value  any
+	Params Params
+}
+
+// InnerList is an array of items with parameters (RFC 8941 Section 3.1.1).
+type InnerList struct {
+	Items  []Item
+	Params Params
+}
+
+// Member is a list member or dictionary member value: either an [Item] or an [InnerList].
+type Member any
+
+// List is a Structured Field list (RFC 8941 Section 3.1).
+type List []Member
+
+// DictMember is a single member of a dictionary.
+type DictMember struct {
+	Key   string
+	Value Member
+}
+
+// Dictionary is an ordered Structured Field dictionary (RFC 8941 Section 3.2).
+type Dictionary []DictMember
+
+// Get returns the value of the member with the given key.
+func (d Dictionary) Get(key string) (Member, bool) {
+	for _, m := range d {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the value of the member with the given key, preserving the position of an existing member.
+func (d Dictionary) Set(key string, value Member) Dictionary {
+	for i := range d {
+		if d[i].Key == key {
+			d[i].Value = value
+			return d
+		}
+	}
+	return append(d, DictMember{Key: key, Value: value})
+}
@@ -0,0 +1,216 @@
+package structfield
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDictionary(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Dictionary
+		out  string
+	}{
+		{``, nil, ``},
+		{`en="Applepie", da=:w4ZibGV0w6ZydGUK:`, Dictionary{
+			{Key: "en", Value: Item{Value: "Applepie"}},
+			{Key: "da", Value: Item{Value: []byte("\xc3\x86blet\xc3\xa6rte\n")}},
+		}, `en="Applepie", da=:w4ZibGV0w6ZydGUK:`},
+		{`a=?0, b, c; foo=bar`, Dictionary{
+			{Key: "a", Value: Item{Value: false}},
+			{Key: "b", Value: Item{Value: true}},
+			{Key: "c", Value: Item{Value: true, Params: Params{{Key: "foo", Value: Token("bar")}}}},
+		}, `a=?0, b, c;foo=bar`},
+		{`rating=1.5, feelings=(joy sadness)`, Dictionary{
+			{Key: "rating", Value: Item{Value: 1.5}},
+			{Key: "feelings", Value: InnerList{Items: []Item{{Value: Token("joy")}, {Value: Token("sadness")}}}},
+		}, `rating=1.5, feelings=(joy sadness)`},
+		{`a=(1 2), b=3, c=4;aa=bb, d=(5 6);valid`, Dictionary{
+			{Key: "a", Value: InnerList{Items: []Item{{Value: int64(1)}, {Value: int64(2)}}}},
+			{Key: "b", Value: Item{Value: int64(3)}},
+			{Key: "c", Value: Item{Value: int64(4), Params: Params{{Key: "aa", Value: Token("bb")}}}},
+			{Key: "d", Value: InnerList{Items: []Item{{Value: int64(5)}, {Value: int64(6)}}, Params: Params{{Key: "valid", Value: true}}}},
+		}, `a=(1 2), b=3, c=4;aa=bb, d=(5 6);valid`},
+		{`sig1=("@method" "@path");created=1618884473;keyid="test-key"`, Dictionary{
+			{Key: "sig1", Value: InnerList{
+				Items:  []Item{{Value: "@method"}, {Value: "@path"}},
+				Params: Params{{Key: "created", Value: int64(1618884473)}, {Key: "keyid", Value: "test-key"}},
+			}},
+		}, `sig1=("@method" "@path");created=1618884473;keyid="test-key"`},
+		{`  a=1 ,	b=2  `, Dictionary{
+			{Key: "a", Value: Item{Value: int64(1)}},
+			{Key: "b", Value: Item{Value: int64(2)}},
+		}, `a=1, b=2`},
+		{`a=1, b=2, a=3`, Dictionary{
+			{Key: "a", Value: Item{Value: int64(3)}},
+			{Key: "b", Value: Item{Value: int64(2)}},
+		}, `a=3, b=2`},
+		{`a=( 1  2 )`, Dictionary{
+			{Key: "a", Value: InnerList{Items: []Item{{Value: int64(1)}, {Value: int64(2)}}}},
+		}, `a=(1 2)`},
+		{`a="q\"s\\"`, Dictionary{
+			{Key: "a", Value: Item{Value: `q"s\`}},
+		}, `a="q\"s\\"`},
+		{`a=:aGk:`, Dictionary{
+			{Key: "a", Value: Item{Value: []byte("hi")}},
+		}, `a=:aGk=:`},
+		{`a=-0.250, b=*tok/en:x`, Dictionary{
+			{Key: "a", Value: Item{Value: -0.25}},
+			{Key: "b", Value: Item{Value: Token("*tok/en:x")}},
+		}, `a=-0.25, b=*tok/en:x`},
+	}
+	for _, tt := range tests {
+		got, err := ParseDictionary(tt.in)
+		if err != nil {
+			t.Errorf("ParseDictionary(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseDictionary(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+		out, err := got.Serialize()
+		if err != nil {
+			t.Errorf("Serialize(%q) error: %v", tt.in, err)
+			continue
+		}
+		if out != tt.out {
+			t.Errorf("Serialize(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestParseDictionary_Errors(t *testing.T) {
+	tests := []string{
+		`a=1,`,
+		`a=1 b=2`,
+		`A=1`,
+		`a=(1 2`,
+		`a=(1,2)`,
+		`a="unterminated`,
+		`a="bad\escape"`,
+		"a=\"\x7f\"",
+		`a=:aGk`,
+		`a=:a$k:`,
+		`a=?2`,
+		`a=1234567890123456`,
+		`a=1234567890123.0`,
+		`a=1.2345`,
+		`a=1.`,
+		`a=-`,
+		`a=@b`,
+		`a=1;B=2`,
+		`,a=1`,
+	}
+	for _, in := range tests {
+		if _, err := ParseDictionary(in); !errors.Is(err, ErrSyntax) {
+			t.Errorf("ParseDictionary(%q) error = %v, want %v", in, err, ErrSyntax)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	in := `sugar, tea, rum;x=1, (foo bar);baz`
+	want := List{
+		Item{Value: Token("sugar")},
+		Item{Value: Token("tea")},
+		Item{Value: Token("rum"), Params: Params{{Key: "x", Value: int64(1)}}},
+		InnerList{Items: []Item{{Value: Token("foo")}, {Value: Token("bar")}}, Params: Params{{Key: "baz", Value: true}}},
+	}
+	got, err := ParseList(in)
+	if err != nil {
+		t.Fatalf("ParseList(%q) error: %v", in, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseList(%q) = %#v, want %#v", in, got, want)
+	}
+	out, err := got.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error: %v", err)
+	}
+	if out != in {
+		t.Errorf("Serialize() = %q, want %q", out, in)
+	}
+
+	for _, in := range []string{`a,`, `a b`, `(a`} {
+		if _, err := ParseList(in); !errors.Is(err, ErrSyntax) {
+			t.Errorf("ParseList(%q) error = %v, want %v", in, err, ErrSyntax)
+		}
+	}
+}
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Item
+	}{
+		{`42`, Item{Value: int64(42)}},
+		{`-999999999999999`, Item{Value: int64(-999999999999999)}},
+		{`4.5;unit="s"`, Item{Value: 4.5, Params: Params{{Key: "unit", Value: "s"}}}},
+		{` "hello" `, Item{Value: "hello"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseItem(tt.in)
+		if err != nil {
+			t.Errorf("ParseItem(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseItem(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{``, `1 2`, `(1)`} {
+		if _, err := ParseItem(in); !errors.Is(err, ErrSyntax) {
+			t.Errorf("ParseItem(%q) error = %v, want %v", in, err, ErrSyntax)
+		}
+	}
+}
+
+func TestSerialize_Errors(t *testing.T) {
+	tests := []any{
+		Item{Value: int64(1_000_000_000_000_000)},
+		Item{Value: 1e12},
+		Item{Value: "non-ascii é"},
+		Item{Value: Token("1abc")},
+		Item{Value: Token("a b")},
+		Item{Value: struct{}{}},
+		Item{Value: int64(1), Params: Params{{Key: "Upper", Value: true}}},
+		Dictionary{{Key: "", Value: Item{Value: int64(1)}}},
+		Dictionary{{Key: "a", Value: "not a member"}},
+	}
+	for _, v := range tests {
+		var err error
+		switch v := v.(type) {
+		case Item:
+			_, err = v.Serialize()
+		case Dictionary:
+			_, err = v.Serialize()
+		}
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Serialize(%#v) error = %v, want %v", v, err, ErrInvalidValue)
+		}
+	}
+}
+
+func TestSerializeDecimal(t *testing.T) {
+	tests := []struct {
+		in  float64
+		out string
+	}{
+		{1, "1.0"},
+		{1.5, "1.5"},
+		{0.0005, "0.0"},
+		{0.0015, "0.002"},
+		{-12.3456, "-12.346"},
+	}
+	for _, tt := range tests {
+		got, err := Item{Value: tt.in}.Serialize()
+		if err != nil {
+			t.Errorf("Serialize(%v) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.out {
+			t.Errorf("Serialize(%v) = %q, want %q", tt.in, got, tt.out)
+		}
+	}
+}
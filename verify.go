@@ -0,0 +1,243 @@
+package httpsign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+// verifier verifies the signatures of a message.
+type verifier struct {
+	resolver        KeyResolver
+	maxAge          time.Duration
+	maxSkew         time.Duration
+	now             func() time.Time // if nil, time.Now is used
+	nonces          NonceStore       // if nil, nonces are not checked
+	required        []componentID    // components every signature must cover
+	labels          []string         // if empty, every signature is verified
+	optionalCreated bool             // whether signatures without a created parameter are accepted
+}
+
+// verify verifies the signatures of the message, returning their results.
+func (v verifier) verify(ctx context.Context, m message) ([]Result, error) {
+	ctx = context.WithValue(ctx, headerContextKey{}, m.header)
+	inputs, err := parseDictionaryField(m.header, signatureInputHeader)
+	if err != nil {
+		return nil, &VerificationError{Reason: ReasonMalformedHeader, Err: err}
+	}
+	if len(inputs) == 0 {
+		return nil, &VerificationError{Reason: ReasonMissingHeader, Err: fmt.Errorf("missing %s field", signatureInputHeader)}
+	}
+	sigs, err := parseDictionaryField(m.header, signatureHeader)
+	if err != nil {
+		return nil, &VerificationError{Reason: ReasonMalformedHeader, Err: err}
+	}
+	if len(v.labels) > 0 {
+		selected := make(structfield.Dictionary, 0, len(v.labels))
+		for _, l := range v.labels {
+			in, ok := inputs.Get(l)
+			if !ok {
+				return nil, &VerificationError{Reason: ReasonMissingHeader, Label: l, Err: fmt.Errorf("missing %s member", signatureInputHeader)}
+			}
+			selected = append(selected, structfield.DictMember{Key: l, Value: in})
+		}
+		inputs = selected
+	}
+	results := make([]Result, 0, len(inputs))
+	params := make([]signatureParams, 0, len(inputs))
+	for _, in := range inputs {
+		res, sp, err := v.verifySignature(ctx, m, in, sigs)
+		if err != nil {
+			var verr *VerificationError
+			if errors.As(err, &verr) {
+				verr.Label = in.Key
+			}
+			return nil, err
+		}
+		results = append(results, res)
+		params = append(params, sp)
+	}
+	// Nonces are recorded once every signature is verified, so that a rejected message does not use them up.
+	for i, sp := range params {
+		if err := v.checkNonce(ctx, sp); err != nil {
+			if errors.Is(err, ErrReplayedSignature) {
+				return nil, &VerificationError{Reason: ReasonReplayed, Label: results[i].Label, KeyID: sp.keyID, Err: err}
+			}
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// verifySignature verifies the signature with the Signature-Input member in, returning its result and parameters.
+// The nonce of the signature is not recorded.
+func (v verifier) verifySignature(ctx context.Context, m message, in structfield.DictMember, sigs structfield.Dictionary) (Result, signatureParams, error) {
+	params, ok := in.Value.(structfield.InnerList)
+	if !ok {
+		return Result{}, signatureParams{}, &VerificationError{Reason: ReasonMalformedHeader, Err: fmt.Errorf("invalid %s member", signatureInputHeader)}
+	}
+	sp, err := parseSignatureParams(params)
+	if err != nil {
+		return Result{}, signatureParams{}, &VerificationError{Reason: ReasonMalformedHeader, Err: err}
+	}
+	fail := func(reason Reason, err error) *VerificationError {
+		return &VerificationError{Reason: reason, KeyID: sp.keyID, Err: err}
+	}
+	for _, c := range v.required {
+		if !slices.Contains(sp.components, c) {
+			verr := fail(ReasonMissingComponent, errors.New("required component is not covered"))
+			verr.Component = c.String()
+			return Result{}, signatureParams{}, verr
+		}
+	}
+
+	if err := v.checkFreshness(sp); err != nil {
+		return Result{}, signatureParams{}, fail(freshnessReason(err), err)
+	}
+	member, ok := sigs.Get(in.Key)
+	if !ok {
+		return Result{}, signatureParams{}, fail(ReasonMissingHeader, fmt.Errorf("missing %s member", signatureHeader))
+	}
+	sig, _ := member.(structfield.Item)
+	sigBytes, ok := sig.Value.([]byte)
+	if !ok {
+		return Result{}, signatureParams{}, fail(ReasonMalformedHeader, errors.New("signature is not a byte sequence"))
+	}
+	key, err := v.resolver.Resolve(ctx, sp.keyID)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Result{}, signatureParams{}, fail(ReasonUnknownKey, err)
+	}
+	if errors.Is(err, ErrUntrustedKey) {
+		return Result{}, signatureParams{}, fail(ReasonUntrustedKey, err)
+	}
+	if err != nil {
+		return Result{}, signatureParams{}, fmt.Errorf("resolve key %q: %w", sp.keyID, err)
+	}
+	if alg := algorithm(key); sp.alg != "" && alg != "" && sp.alg != alg {
+		return Result{}, signatureParams{}, fail(ReasonAlgorithmMismatch, fmt.Errorf("algorithm %q does not match the key algorithm %q", sp.alg, alg))
+	}
+	base, err := signatureBase(m, params)
+	if err != nil {
+		var cerr *componentError
+		if errors.As(err, &cerr) {
+			verr := fail(ReasonUnsupportedComponent, cerr.err)
+			verr.Component = cerr.id
+			if errors.Is(err, errMissingField) {
+				verr.Reason = ReasonMissingComponent
+			}
+			return Result{}, signatureParams{}, verr
+		}
+		return Result{}, signatureParams{}, fail(ReasonMalformedHeader, err)
+	}
+
+	valid, err := verify(ctx, key, base, sigBytes)
+	if errors.Is(err, ErrMalformedSignature) {
+		return Result{}, signatureParams{}, fail(ReasonMalformedSignature, err)
+	}
+	if errors.Is(err, ErrUntrustedKey) {
+		return Result{}, signatureParams{}, fail(ReasonUntrustedKey, err)
+	}
+	if err != nil {
+		return Result{}, signatureParams{}, fmt.Errorf("verify signature %q: %w", in.Key, err)
+	}
+	if !valid {
+		return Result{}, signatureParams{}, fail(ReasonSignatureMismatch, nil)
+	}
+
+	res := Result{
+		Label:     in.Key,
+		KeyID:     sp.keyID,
+		Algorithm: sp.alg,
+		Created:   sp.created,
+		Expires:   sp.expires,
+	}
+	if res.Algorithm == "" {
+		res.Algorithm = algorithm(key)
+	}
+	for _, c := range sp.components {
+		res.Components = append(res.Components, c.String())
+	}
+	return res, sp, nil
+}
+
+// checkFreshness checks that the signature was created within the freshness window.
+func (v verifier) checkFreshness(sp signatureParams) error {
+	if sp.created.IsZero() && !v.optionalCreated {
+		return errMissingCreated
+	}
+	if v.nonces != nil && sp.nonce == "" {
+		return errMissingNonce
+	}
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+	if sp.created.After(now.Add(v.maxSkew)) {
+		return ErrFutureSignature
+	}
+	if maxAge := v.maxAgeOf(sp); maxAge > 0 && !sp.created.IsZero() && now.Sub(sp.created) > maxAge {
+		return ErrStaleSignature
+	}
+	if !sp.expires.IsZero() && now.After(sp.expires) {
+		return ErrExpiredSignature
+	}
+	return nil
+}
+
+// maxAgeOf returns the maximum age of the signature. Signatures whose nonce is recorded
+// must expire, so that the nonce is not kept forever.
+func (v verifier) maxAgeOf(sp signatureParams) time.Duration {
+	if v.maxAge == 0 && v.nonces != nil && sp.expires.IsZero() {
+		return DefaultMaxAge
+	}
+	return v.maxAge
+}
+
+var (
+	errMissingCreated = errors.New("missing created parameter")
+	errMissingNonce   = errors.New("missing nonce parameter")
+)
+
+// freshnessReason returns the reason of an error returned by checkFreshness.
+func freshnessReason(err error) Reason {
+	switch {
+	case errors.Is(err, ErrFutureSignature):
+		return ReasonNotYetValid
+	case errors.Is(err, ErrStaleSignature), errors.Is(err, ErrExpiredSignature):
+		return ReasonExpired
+	default:
+		return ReasonMissingParameter
+	}
+}
+
+// checkNonce records the nonce of a verified signature, rejecting nonces that were already used.
+func (v verifier) checkNonce(ctx context.Context, sp signatureParams) error {
+	if v.nonces == nil {
+		return nil
+	}
+	expiry := sp.expires
+	if maxAge := v.maxAgeOf(sp); maxAge > 0 && (expiry.IsZero() || sp.created.Add(maxAge).Before(expiry)) {
+		expiry = sp.created.Add(maxAge)
+	}
+	fresh, err := v.nonces.Add(ctx, sp.nonce, expiry)
+	if err != nil {
+		return fmt.Errorf("store nonce: %w", err)
+	}
+	if !fresh {
+		return ErrReplayedSignature
+	}
+	return nil
+}
+
+// digestError returns the verification error for an error returned by verifyContentDigest.
+func digestError(err error) error {
+	reason := ReasonMalformedHeader
+	if errors.Is(err, errDigestMismatch) {
+		reason = ReasonDigestMismatch
+	}
+	return &VerificationError{Reason: reason, Component: `"content-digest"`, Err: err}
+}
@@ -0,0 +1,123 @@
+package x509
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/denpeshkov/httpsign"
+	"github.com/denpeshkov/httpsign/structfield"
+)
+
+// Header fields of certificates (RFC 9440 Section 2).
+const (
+	DefaultCertHeader  = "Client-Cert"
+	DefaultChainHeader = "Client-Cert-Chain"
+)
+
+// Resolver is a [httpsign.KeyResolver] resolving the [Verifier] of the certificate chain sent in the header fields
+// of the message being verified. As specified by RFC 9440, the certificate field is a byte sequence of
+// the DER encoded certificate, and the optional chain field is a list of byte sequences of the intermediates.
+// The keyid signature parameter, if present, must be the key ID of the certificate, so that it cannot name
+// the holder of another certificate issued by the same roots.
+//
+// Messages without the certificate field are rejected as signed by an unknown key,
+// and messages with invalid certificates as signed by an untrusted key.
+type Resolver struct {
+	// Roots are the trusted root certificates, such as an internal CA.
+	// If nil, the system roots are used.
+	Roots *x509.CertPool
+	// Intermediates are intermediate certificates used to build the chain, in addition to those of the chain field.
+	Intermediates *x509.CertPool
+	// KeyUsages are the acceptable extended key usages of the certificate.
+	// By default, it is x509.ExtKeyUsageClientAuth; x509.ExtKeyUsageAny accepts any usage.
+	KeyUsages []x509.ExtKeyUsage
+	// Now returns the time at which the chain is validated. If nil, time.Now is used.
+	Now func() time.Time
+	// Algorithm is the algorithm of the certificates. See [NewChainVerifier].
+	Algorithm string
+	// CertHeader is the name of the certificate field. By default, it is DefaultCertHeader.
+	CertHeader string
+	// ChainHeader is the name of the chain field. By default, it is DefaultChainHeader.
+	ChainHeader string
+	// KeyID returns the key ID of a certificate, which the keyid signature parameter must match.
+	// If nil, the common name of the subject is used.
+	KeyID func(cert *x509.Certificate) string
+}
+
+// NewResolver returns a new [Resolver] given the trusted root certificates.
+func NewResolver(roots *x509.CertPool) *Resolver {
+	return &Resolver{
+		Roots:       roots,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		CertHeader:  DefaultCertHeader,
+		ChainHeader: DefaultChainHeader,
+	}
+}
+
+// Resolve returns the Verifier of the certificate chain sent in the message being verified.
+// Signatures whose key ID is not the key ID of the certificate are rejected as signed by an untrusted key.
+func (r *Resolver) Resolve(ctx context.Context, keyID string) (httpsign.Verifier, error) {
+	h, ok := httpsign.HeaderFromContext(ctx)
+	if !ok {
+		return nil, errors.New("x509: no message is being verified")
+	}
+	if len(h.Values(r.CertHeader)) == 0 {
+		return nil, fmt.Errorf("x509: missing %s field: %w", r.CertHeader, httpsign.ErrKeyNotFound)
+	}
+	item, err := structfield.ParseItem(strings.Join(h.Values(r.CertHeader), ", "))
+	if err != nil {
+		return nil, fmt.Errorf("x509: %w: %s field: %w", httpsign.ErrUntrustedKey, r.CertHeader, err)
+	}
+	cert, err := parseCertificate(item)
+	if err != nil {
+		return nil, fmt.Errorf("x509: %w: %s field: %w", httpsign.ErrUntrustedKey, r.CertHeader, err)
+	}
+	if keyID != "" {
+		if want := r.keyID(cert); keyID != want {
+			return nil, fmt.Errorf("x509: %w: key ID %q is not the key ID %q of the certificate", httpsign.ErrUntrustedKey, keyID, want)
+		}
+	}
+	chain := []*x509.Certificate{cert}
+	if values := h.Values(r.ChainHeader); len(values) > 0 {
+		list, err := structfield.ParseList(strings.Join(values, ", "))
+		if err != nil {
+			return nil, fmt.Errorf("x509: %w: %s field: %w", httpsign.ErrUntrustedKey, r.ChainHeader, err)
+		}
+		for _, m := range list {
+			item, _ := m.(structfield.Item)
+			cert, err := parseCertificate(item)
+			if err != nil {
+				return nil, fmt.Errorf("x509: %w: %s field: %w", httpsign.ErrUntrustedKey, r.ChainHeader, err)
+			}
+			chain = append(chain, cert)
+		}
+	}
+
+	v, err := NewChainVerifier(chain, r.Algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", httpsign.ErrUntrustedKey, err)
+	}
+	v.Roots, v.Intermediates, v.KeyUsages, v.Now = r.Roots, r.Intermediates, r.KeyUsages, r.Now
+	return v, nil
+}
+
+// keyID returns the key ID of the certificate.
+func (r *Resolver) keyID(cert *x509.Certificate) string {
+	if r.KeyID != nil {
+		return r.KeyID(cert)
+	}
+	return cert.Subject.CommonName
+}
+
+// parseCertificate parses the DER encoded certificate of the byte sequence item.
+func parseCertificate(item structfield.Item) (*x509.Certificate, error) {
+	der, ok := item.Value.([]byte)
+	if !ok {
+		return nil, errors.New("certificate is not a byte sequence")
+	}
+	return x509.ParseCertificate(der)
+}
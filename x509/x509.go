@@ -0,0 +1,145 @@
+// Package x509 provides a [httpsign.Verifier] of the public key of an X.509 certificate,
+// which validates the certificate chain each time a signature is verified,
+// and a [httpsign.KeyResolver] of certificate chains sent in the header fields of messages.
+//
+// Certificates which fail validation are rejected with an error wrapping [httpsign.ErrUntrustedKey].
+package x509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/denpeshkov/httpsign"
+	"github.com/denpeshkov/httpsign/keys"
+)
+
+// Verifier verifies message signatures using the public key of a certificate.
+// Before each verification, the certificate chain is validated against Roots at the current time,
+// and the key usages of the certificate are checked.
+// It is safe for concurrent use by multiple goroutines.
+type Verifier struct {
+	// Roots are the trusted root certificates, such as an internal CA.
+	// If nil, the system roots are used.
+	Roots *x509.CertPool
+	// Intermediates are intermediate certificates used to build the chain,
+	// in addition to those the Verifier was created with.
+	Intermediates *x509.CertPool
+	// KeyUsages are the acceptable extended key usages of the certificate.
+	// By default, it is x509.ExtKeyUsageClientAuth; x509.ExtKeyUsageAny accepts any usage.
+	KeyUsages []x509.ExtKeyUsage
+	// Now returns the time at which the chain is validated. If nil, time.Now is used.
+	Now func() time.Time
+
+	cert          *x509.Certificate
+	intermediates []*x509.Certificate
+	verifier      httpsign.Verifier
+}
+
+// NewVerifier returns a new [Verifier] of the certificate for the algorithm.
+// See [NewChainVerifier].
+func NewVerifier(cert *x509.Certificate, alg string) (*Verifier, error) {
+	return NewChainVerifier([]*x509.Certificate{cert}, alg)
+}
+
+// NewChainVerifier returns a new [Verifier] of the first certificate of the chain for the algorithm.
+// The other certificates of the chain are used as intermediates.
+// The algorithm is an HTTP Signature Algorithms registry name. If empty, it is derived from ECDSA P-256,
+// ECDSA P-384 and Ed25519 keys; RSA keys require an algorithm, as they can be used with several.
+func NewChainVerifier(chain []*x509.Certificate, alg string) (*Verifier, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("x509: empty certificate chain")
+	}
+	cert := chain[0]
+	if alg == "" {
+		alg = algorithm(cert.PublicKey)
+	}
+	v, err := keys.NewVerifier(cert.PublicKey, alg)
+	if err != nil {
+		return nil, fmt.Errorf("x509: %w", err)
+	}
+	return &Verifier{
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		cert:          cert,
+		intermediates: chain[1:],
+		verifier:      v,
+	}, nil
+}
+
+// Verify validates the certificate chain and verifies the signature of a message using the public key of the certificate.
+// If the chain is not valid, it returns false and an error wrapping [httpsign.ErrUntrustedKey].
+func (v *Verifier) Verify(message []byte, signature []byte) (bool, error) {
+	if err := v.Validate(); err != nil {
+		return false, err
+	}
+	return v.verifier.Verify(message, signature)
+}
+
+// Validate validates the certificate chain at the current time.
+// If the chain is not valid, the returned error wraps [httpsign.ErrUntrustedKey].
+func (v *Verifier) Validate() error {
+	if v.cert.KeyUsage != 0 && v.cert.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("x509: %w: certificate key usage does not allow digital signatures", httpsign.ErrUntrustedKey)
+	}
+	intermediates := v.Intermediates
+	if len(v.intermediates) > 0 {
+		if intermediates == nil {
+			intermediates = x509.NewCertPool()
+		} else {
+			intermediates = intermediates.Clone()
+		}
+		for _, c := range v.intermediates {
+			intermediates.AddCert(c)
+		}
+	}
+	var now time.Time // the zero time is the current time
+	if v.Now != nil {
+		now = v.Now()
+	}
+	_, err := v.cert.Verify(x509.VerifyOptions{
+		Roots:         v.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     v.KeyUsages,
+	})
+	if err != nil {
+		return fmt.Errorf("x509: %w: %w", httpsign.ErrUntrustedKey, err)
+	}
+	return nil
+}
+
+// Algorithm returns the HTTP Signature Algorithms registry name of the algorithm.
+func (v *Verifier) Algorithm() string {
+	return v.verifier.(interface{ Algorithm() string }).Algorithm()
+}
+
+// Public returns the public key of the certificate.
+func (v *Verifier) Public() crypto.PublicKey {
+	return v.cert.PublicKey
+}
+
+// Certificate returns the certificate.
+func (v *Verifier) Certificate() *x509.Certificate {
+	return v.cert
+}
+
+// algorithm returns the algorithm of the public key, or an empty string if it has several.
+func algorithm(pub crypto.PublicKey) string {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return httpsign.AlgorithmECDSAP256SHA256
+		case elliptic.P384():
+			return httpsign.AlgorithmECDSAP384SHA384
+		}
+	case ed25519.PublicKey:
+		return httpsign.AlgorithmEd25519
+	}
+	return ""
+}
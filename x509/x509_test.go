@@ -0,0 +1,343 @@
+package x509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denpeshkov/httpsign"
+	hsed25519 "github.com/denpeshkov/httpsign/ed25519"
+	"github.com/denpeshkov/httpsign/keys"
+	hsrsa "github.com/denpeshkov/httpsign/rsa"
+)
+
+// pki is a root and an intermediate CA issuing leaf certificates.
+type pki struct {
+	roots     *x509.CertPool
+	inter     *x509.Certificate
+	interPriv crypto.Signer
+}
+
+func newPKI(t *testing.T) *pki {
+	t.Helper()
+	rootPriv := generateKey(t)
+	root := issue(t, &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageCertSign}, nil, rootPriv, rootPriv.Public())
+	interPriv := generateKey(t)
+	inter := issue(t, &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageCertSign}, root, rootPriv, interPriv.Public())
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	return &pki{roots: roots, inter: inter, interPriv: interPriv}
+}
+
+// leaf issues a client certificate for the public key.
+func (p *pki) leaf(t *testing.T, pub crypto.PublicKey, modify func(tmpl *x509.Certificate)) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if modify != nil {
+		modify(tmpl)
+	}
+	return issue(t, tmpl, p.inter, p.interPriv, pub)
+}
+
+func TestVerifier(t *testing.T) {
+	p := newPKI(t)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	sgn, err := hsed25519.NewSigner(priv)
+	if err != nil {
+		t.Fatalf("NewSigner() error: %v", err)
+	}
+	msg := []byte("test")
+	sig, err := sgn.Sign(msg)
+	if err != nil {
+		t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+	}
+
+	tests := []struct {
+		name   string
+		leaf   func(tmpl *x509.Certificate)
+		modify func(v *Verifier)
+		err    error
+	}{
+		{name: "valid"},
+		{
+			name:   "any key usage",
+			leaf:   func(tmpl *x509.Certificate) { tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth} },
+			modify: func(v *Verifier) { v.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny} },
+		},
+		{
+			name:   "default clock",
+			modify: func(v *Verifier) { v.Now = nil },
+		},
+		{
+			name:   "expired",
+			modify: func(v *Verifier) { v.Now = func() time.Time { return time.Now().Add(2 * time.Hour) } },
+			err:    httpsign.ErrUntrustedKey,
+		},
+		{
+			name:   "not yet valid",
+			modify: func(v *Verifier) { v.Now = func() time.Time { return time.Now().Add(-2 * time.Hour) } },
+			err:    httpsign.ErrUntrustedKey,
+		},
+		{
+			name: "extended key usage",
+			leaf: func(tmpl *x509.Certificate) { tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth} },
+			err:  httpsign.ErrUntrustedKey,
+		},
+		{
+			name: "key usage",
+			leaf: func(tmpl *x509.Certificate) { tmpl.KeyUsage = x509.KeyUsageKeyEncipherment },
+			err:  httpsign.ErrUntrustedKey,
+		},
+		{
+			name:   "unknown root",
+			modify: func(v *Verifier) { v.Roots = newPKI(t).roots },
+			err:    httpsign.ErrUntrustedKey,
+		},
+		{
+			name:   "missing intermediate",
+			modify: func(v *Verifier) { v.intermediates = nil },
+			err:    httpsign.ErrUntrustedKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewChainVerifier([]*x509.Certificate{p.leaf(t, pub, tt.leaf), p.inter}, "")
+			if err != nil {
+				t.Fatalf("NewChainVerifier() error: %v", err)
+			}
+			v.Roots = p.roots
+			if tt.modify != nil {
+				tt.modify(v)
+			}
+			ok, err := v.Verify(msg, sig)
+			if !errors.Is(err, tt.err) || ok != (tt.err == nil) {
+				t.Errorf("Verify() = %t, %v, want error %v", ok, err, tt.err)
+			}
+		})
+	}
+
+	v, err := NewChainVerifier([]*x509.Certificate{p.leaf(t, pub, nil), p.inter}, "")
+	if err != nil {
+		t.Fatalf("NewChainVerifier() error: %v", err)
+	}
+	v.Roots = p.roots
+	if ok, err := v.Verify([]byte("forged"), sig); ok || err != nil {
+		t.Errorf("Verify() of a forged message = %t, %v, want false, nil", ok, err)
+	}
+	if alg := v.Algorithm(); alg != httpsign.AlgorithmEd25519 {
+		t.Errorf("Algorithm() = %q, want %q", alg, httpsign.AlgorithmEd25519)
+	}
+	if got := v.Public(); !pub.Equal(got) {
+		t.Errorf("Public() = %x, want %x", got, pub)
+	}
+}
+
+func TestNewVerifier_RSA(t *testing.T) {
+	p := newPKI(t)
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	cert := p.leaf(t, priv.Public(), nil)
+	if _, err := NewVerifier(cert, ""); !errors.Is(err, keys.ErrUnsupportedAlgorithm) {
+		t.Errorf("NewVerifier(RSA certificate, %q) error = %v, want %v", "", err, keys.ErrUnsupportedAlgorithm)
+	}
+	if _, err := NewVerifier(cert, httpsign.AlgorithmEd25519); !errors.Is(err, keys.ErrAlgorithmMismatch) {
+		t.Errorf("NewVerifier(RSA certificate, %q) error = %v, want %v", httpsign.AlgorithmEd25519, err, keys.ErrAlgorithmMismatch)
+	}
+
+	v, err := NewVerifier(cert, httpsign.AlgorithmRSAPKCSSHA256)
+	if err != nil {
+		t.Fatalf("NewVerifier() error: %v", err)
+	}
+	v.Roots = p.roots
+	v.Intermediates = x509.NewCertPool()
+	v.Intermediates.AddCert(p.inter)
+	sgn, err := hsrsa.NewPKCSSigner(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("NewPKCSSigner() error: %v", err)
+	}
+	msg := []byte("test")
+	sig, err := sgn.Sign(msg)
+	if err != nil {
+		t.Fatalf("Signer.Sign(%s) error: %v", msg, err)
+	}
+	if ok, err := v.Verify(msg, sig); err != nil || !ok {
+		t.Errorf("Signed message not verified by Verifier: %v", err)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	p := newPKI(t)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	sgn, err := hsed25519.NewSigner(priv)
+	if err != nil {
+		t.Fatalf("NewSigner() error: %v", err)
+	}
+	leaf := p.leaf(t, pub, nil)
+	serverLeaf := p.leaf(t, pub, func(tmpl *x509.Certificate) {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	})
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	otherLeaf := p.leaf(t, otherPub, nil)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		keyID  string
+		reason httpsign.Reason
+	}{
+		{
+			name:   "valid",
+			header: http.Header{"Client-Cert": {sfBinary(leaf.Raw)}, "Client-Cert-Chain": {sfBinary(p.inter.Raw)}},
+		},
+		{
+			name:   "key id",
+			header: http.Header{"Client-Cert": {sfBinary(leaf.Raw)}, "Client-Cert-Chain": {sfBinary(p.inter.Raw)}},
+			keyID:  leaf.Subject.CommonName,
+		},
+		{
+			name:   "other key id",
+			header: http.Header{"Client-Cert": {sfBinary(leaf.Raw)}, "Client-Cert-Chain": {sfBinary(p.inter.Raw)}},
+			keyID:  otherLeaf.Subject.CommonName,
+			reason: httpsign.ReasonUntrustedKey,
+		},
+		{
+			name:   "missing certificate",
+			header: http.Header{"Client-Cert-Chain": {sfBinary(p.inter.Raw)}},
+			reason: httpsign.ReasonUnknownKey,
+		},
+		{
+			name:   "missing chain",
+			header: http.Header{"Client-Cert": {sfBinary(leaf.Raw)}},
+			reason: httpsign.ReasonUntrustedKey,
+		},
+		{
+			name:   "invalid certificate",
+			header: http.Header{"Client-Cert": {sfBinary([]byte("cert"))}, "Client-Cert-Chain": {sfBinary(p.inter.Raw)}},
+			reason: httpsign.ReasonUntrustedKey,
+		},
+		{
+			name:   "malformed chain",
+			header: http.Header{"Client-Cert": {sfBinary(leaf.Raw)}, "Client-Cert-Chain": {"not base64"}},
+			reason: httpsign.ReasonUntrustedKey,
+		},
+		{
+			name:   "server certificate",
+			header: http.Header{"Client-Cert": {sfBinary(serverLeaf.Raw)}, "Client-Cert-Chain": {sfBinary(p.inter.Raw)}},
+			reason: httpsign.ReasonUntrustedKey,
+		},
+		{
+			name:   "other key",
+			header: http.Header{"Client-Cert": {sfBinary(otherLeaf.Raw)}, "Client-Cert-Chain": {sfBinary(p.inter.Raw)}},
+			reason: httpsign.ReasonSignatureMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := httpsign.NewResolverMiddleware(NewResolver(p.roots))
+			var verr *httpsign.VerificationError
+			m.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+				errors.As(err, &verr)
+				httpsign.DefaultErrorHandler(w, r, err)
+			}
+			tr := httpsign.NewTransport(sgn)
+			tr.KeyID = tt.keyID
+			tr.Headers = []string{"Client-Cert"}
+			tr.MissingHeaders = httpsign.MissingHeaderSkip
+			h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			tr.Base = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				return w.Result(), nil
+			})
+
+			r := httptest.NewRequest("GET", "http://example.com/", nil)
+			r.Header = tt.header
+			resp, err := tr.RoundTrip(r)
+			if err != nil {
+				t.Fatalf("RoundTrip() error: %v", err)
+			}
+			resp.Body.Close()
+			if tt.reason == 0 {
+				if resp.StatusCode != http.StatusOK {
+					t.Errorf("RoundTrip() code: %d, want %d (error: %v)", resp.StatusCode, http.StatusOK, verr)
+				}
+				return
+			}
+			if verr == nil || verr.Reason != tt.reason {
+				t.Errorf("RoundTrip() error = %v, want reason %v", verr, tt.reason)
+			}
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("RoundTrip() code: %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func sfBinary(b []byte) string {
+	return ":" + base64.StdEncoding.EncodeToString(b) + ":"
+}
+
+func generateKey(t *testing.T) crypto.Signer {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	return priv
+}
+
+var serial atomic.Int64
+
+// issue issues a certificate valid for an hour from the template.
+// If parent is nil, the certificate is self-signed.
+func issue(t *testing.T, tmpl, parent *x509.Certificate, parentPriv crypto.Signer, pub crypto.PublicKey) *x509.Certificate {
+	t.Helper()
+	tmpl.SerialNumber = big.NewInt(serial.Add(1))
+	tmpl.Subject = pkix.Name{CommonName: tmpl.SerialNumber.String()}
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.BasicConstraintsValid = true
+	if parent == nil {
+		parent = tmpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, parentPriv)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error: %v", err)
+	}
+	return cert
+}